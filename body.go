@@ -0,0 +1,202 @@
+package hrq
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// bodySource opens a request body.
+type bodySource struct {
+	reader io.Reader
+	open   func() (io.ReadCloser, error)
+	// length is -1 when it is unknown.
+	length int64
+	// once is true when the body can not be rewound.
+	once bool
+}
+
+func bytesSource(b []byte) *bodySource {
+	return &bodySource{
+		open: func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(b)), nil
+		},
+		length: int64(len(b)),
+	}
+}
+
+// readerSource makes a bodySource from io.Reader.
+// An io.Seeker is rewound to the current position whenever it is opened.
+// Other readers can be sent only once and are streamed with chunked encoding.
+func readerSource(reader io.Reader) *bodySource {
+	if b, ok := reader.(*bytes.Buffer); ok {
+		src := bytesSource(b.Bytes())
+		src.reader = reader
+		return src
+	}
+	if seeker, ok := reader.(io.Seeker); ok {
+		if src, err := seekerSource(reader, seeker); err == nil {
+			return src
+		}
+	}
+	rc, ok := reader.(io.ReadCloser)
+	if !ok {
+		rc = ioutil.NopCloser(reader)
+	}
+	return &bodySource{
+		reader: reader,
+		open: func() (io.ReadCloser, error) {
+			return rc, nil
+		},
+		length: -1,
+		once:   true,
+	}
+}
+
+func seekerSource(reader io.Reader, seeker io.Seeker) (*bodySource, error) {
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	end, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = seeker.Seek(start, io.SeekStart); err != nil {
+		return nil, err
+	}
+	// The reader is not closed by http.Client
+	// because it is read again on redirects and retries.
+	body := ioutil.NopCloser(reader)
+	return &bodySource{
+		reader: reader,
+		open: func() (io.ReadCloser, error) {
+			_, err := seeker.Seek(start, io.SeekStart)
+			return body, err
+		},
+		length: end - start,
+	}, nil
+}
+
+// compressReader compresses src while it is read.
+type compressReader struct {
+	src    io.ReadCloser
+	writer io.WriteCloser
+	buffer bytes.Buffer
+	chunk  []byte
+	eof    bool
+}
+
+func newCompressReader(src io.ReadCloser, coding string, level int) (io.ReadCloser, error) {
+	c := &compressReader{
+		src:   src,
+		chunk: make([]byte, 32*1024),
+	}
+	writer, err := newEncoder(coding, level, &c.buffer)
+	if err != nil {
+		return nil, err
+	}
+	c.writer = writer
+	return c, nil
+}
+
+func (c *compressReader) Read(p []byte) (int, error) {
+	for c.buffer.Len() == 0 && !c.eof {
+		n, err := c.src.Read(c.chunk)
+		if n > 0 {
+			if _, werr := c.writer.Write(c.chunk[:n]); werr != nil {
+				return 0, werr
+			}
+		}
+		if err == io.EOF {
+			if err = c.writer.Close(); err != nil {
+				return 0, err
+			}
+			c.eof = true
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if c.buffer.Len() == 0 {
+		return 0, io.EOF
+	}
+	return c.buffer.Read(p)
+}
+
+func (c *compressReader) Close() error {
+	if !c.eof {
+		c.writer.Close()
+	}
+	return c.src.Close()
+}
+
+// setSource sets the request body opened by src.
+// The body is compressed on the fly by Request.Compression or Request.Gzip
+// unless it is smaller than Request.MinCompressSize.
+func (r *Request) setSource(src *bodySource) error {
+	open := src.open
+	length := src.length
+	r.body = src
+	r.contentCoding = r.compression()
+	if length == 0 || length > 0 && length < r.MinCompressSize {
+		r.contentCoding = ""
+	}
+	if r.contentCoding != "" {
+		coding, level := r.contentCoding, r.CompressionLevel
+		encoder, err := newEncoder(coding, level, ioutil.Discard)
+		if err != nil {
+			return err
+		}
+		encoder.Close()
+		open = func() (io.ReadCloser, error) {
+			body, err := src.open()
+			if err != nil {
+				return nil, err
+			}
+			return newCompressReader(body, coding, level)
+		}
+		length = -1
+		r.SetHeader("Content-Encoding", coding)
+	}
+	if length == 0 {
+		r.Body = http.NoBody
+		r.ContentLength = 0
+		r.GetBody = func() (io.ReadCloser, error) {
+			return http.NoBody, nil
+		}
+		return nil
+	}
+	body, err := open()
+	if err != nil {
+		return err
+	}
+	r.Body = body
+	r.ContentLength = length
+	r.GetBody = nil
+	if !src.once {
+		r.GetBody = open
+	}
+	return nil
+}
+
+// compression returns the content-coding to compress the request body.
+func (r *Request) compression() string {
+	if r.identity {
+		return ""
+	}
+	if r.Compression != "" {
+		return r.Compression
+	}
+	if r.Gzip {
+		return "gzip"
+	}
+	return ""
+}
+
+// disableCompression makes the request body sent without compression.
+func (r *Request) disableCompression() error {
+	r.identity = true
+	r.DelHeader("Content-Encoding")
+	return r.setSource(r.body)
+}
@@ -0,0 +1,74 @@
+package hrq
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"strings"
+	"sync"
+)
+
+// Codec encodes Request.Data and decodes Response body.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+var codecs = struct {
+	sync.RWMutex
+	m map[string]Codec
+}{
+	m: map[string]Codec{
+		applicationJSON: jsonCodec{},
+		"+json":         jsonCodec{},
+	},
+}
+
+// RegisterCodec registers a codec for a media type like "application/yaml"
+// or a structured syntax suffix like "+cbor".
+// A codec registered for a media type takes precedence over a suffix.
+func RegisterCodec(mediaType string, codec Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.m[strings.ToLower(mediaType)] = codec
+}
+
+// LookupCodec returns the codec for a content-type.
+// Parameters like charset are ignored, and a media type like
+// "application/vnd.api+json" falls back to the codec for "+json".
+// It returns nil when no codec is registered.
+func LookupCodec(contentType string) Codec {
+	mt := mediaType(contentType)
+	codecs.RLock()
+	defer codecs.RUnlock()
+	if codec, ok := codecs.m[mt]; ok {
+		return codec
+	}
+	if i := strings.LastIndexByte(mt, '+'); i >= 0 {
+		return codecs.m[mt[i:]]
+	}
+	return nil
+}
+
+// mediaType returns the lower case media type of a content-type without parameters.
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mt = strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	}
+	return strings.ToLower(mt)
+}
+
+func noCodecError(contentType string) error {
+	return fmt.Errorf("hrq: no codec for content-type %q", contentType)
+}
@@ -0,0 +1,88 @@
+package hrq
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type lineCodec struct{}
+
+func (lineCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(strings.Join(v.([]string), "\n")), nil
+}
+
+func (lineCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*[]string) = strings.Split(string(data), "\n")
+	return nil
+}
+
+func TestLookupCodec(t *testing.T) {
+	RegisterCodec("text/x-lines", lineCodec{})
+	RegisterCodec("+lines", lineCodec{})
+	cases := map[string]Codec{
+		"application/json":                jsonCodec{},
+		"Application/JSON; charset=utf-8": jsonCodec{},
+		"application/vnd.api+json":        jsonCodec{},
+		"application/problem+json":        jsonCodec{},
+		"text/x-lines; charset=utf-8":     lineCodec{},
+		"application/vnd.foo+lines":       lineCodec{},
+		"text/plain":                      nil,
+		"":                                nil,
+	}
+	for contentType, codec := range cases {
+		if LookupCodec(contentType) != codec {
+			t.Fatalf("LookupCodec(%#v) is wrong.", contentType)
+		}
+	}
+}
+
+func TestCodecRequest(t *testing.T) {
+	RegisterCodec("text/x-lines", lineCodec{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		fmt.Fprintf(w, "%s", b)
+	}))
+	defer server.Close()
+	for _, contentType := range []string{"application/json; charset=utf-8", "application/vnd.api+json"} {
+		req, _ := Post(server.URL, map[string]string{"foo": "123"})
+		res, err := req.SetHeader("Content-Type", contentType).Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var data map[string]string
+		if err = res.Decode(&data); err != nil {
+			t.Fatal(err)
+		}
+		if data["foo"] != "123" {
+			t.Fatalf("Decode() is wrong. %s %#v", contentType, data)
+		}
+	}
+	req, _ := Post(server.URL, []string{"a", "b"})
+	res, err := req.SetHeader("Content-Type", "text/x-lines").Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var lines []string
+	res.Decode(&lines)
+	if len(lines) != 2 || lines[1] != "b" {
+		t.Fatalf("Decode() is wrong. %#v", lines)
+	}
+	req, _ = Post(server.URL, "raw text")
+	res, _ = req.SetHeader("Content-Type", "text/plain").Send()
+	text, _ := res.Text()
+	if text != "raw text" {
+		t.Fatalf("string data is wrong. %#v", text)
+	}
+	if err = res.Decode(&lines); err == nil {
+		t.Fatalf("Decode() must fail for text/plain.")
+	}
+	req, _ = Post(server.URL, map[string]string{})
+	if _, err = req.SetHeader("Content-Type", "text/plain").Send(); err == nil {
+		t.Fatalf("Send() must fail for text/plain map.")
+	}
+}
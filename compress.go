@@ -0,0 +1,105 @@
+package hrq
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// acceptEncoding is the value of Accept-Encoding set by AcceptCompression.
+const acceptEncoding = "gzip, deflate, br, zstd"
+
+// newEncoder returns a writer which compresses into w by the content-coding.
+// level 0 means the default level of each algorithm.
+func newEncoder(coding string, level int, w io.Writer) (io.WriteCloser, error) {
+	switch coding {
+	case "gzip":
+		if level == 0 {
+			level = gzip.DefaultCompression
+		}
+		return gzip.NewWriterLevel(w, level)
+	case "deflate":
+		if level == 0 {
+			level = zlib.DefaultCompression
+		}
+		return zlib.NewWriterLevel(w, level)
+	case "br":
+		if level == 0 {
+			level = brotli.DefaultCompression
+		}
+		return brotli.NewWriterLevel(w, level), nil
+	case "zstd":
+		if level == 0 {
+			return zstd.NewWriter(w)
+		}
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	}
+	return nil, fmt.Errorf("hrq: unsupported content-encoding %q", coding)
+}
+
+// newDecoder returns a reader which decompresses r by the content-coding.
+func newDecoder(coding string, r io.Reader) (io.ReadCloser, error) {
+	switch coding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// "deflate" should be zlib format, but some servers send raw deflate.
+		br := bufio.NewReader(r)
+		header, err := br.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	case "br":
+		return ioutil.NopCloser(brotli.NewReader(r)), nil
+	case "zstd":
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case "identity", "":
+		return ioutil.NopCloser(r), nil
+	}
+	return nil, fmt.Errorf("hrq: unsupported content-encoding %q", coding)
+}
+
+// decodedReader decompresses body by Content-Encoding.
+// Stacked encodings like "gzip, br" are decoded in the reverse order.
+type decodedReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func newDecodedReader(body io.Reader, contentEncoding string) (*decodedReader, error) {
+	d := &decodedReader{Reader: body}
+	codings := strings.Split(contentEncoding, ",")
+	for i := len(codings) - 1; i >= 0; i-- {
+		decoder, err := newDecoder(strings.ToLower(strings.TrimSpace(codings[i])), d.Reader)
+		if err != nil {
+			d.Close()
+			return nil, err
+		}
+		d.Reader = decoder
+		d.closers = append(d.closers, decoder)
+	}
+	return d, nil
+}
+
+func (d *decodedReader) Close() error {
+	var err error
+	for i := len(d.closers) - 1; i >= 0; i-- {
+		if e := d.closers[i].Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
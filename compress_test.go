@@ -0,0 +1,210 @@
+package hrq
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+func compressForTest(coding string, b []byte) []byte {
+	var buffer bytes.Buffer
+	var w io.WriteCloser
+	switch coding {
+	case "gzip":
+		w = gzip.NewWriter(&buffer)
+	case "deflate":
+		w = zlib.NewWriter(&buffer)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(&buffer, flate.DefaultCompression)
+	case "br":
+		w = brotli.NewWriter(&buffer)
+	case "zstd":
+		w, _ = zstd.NewWriter(&buffer)
+	}
+	w.Write(b)
+	w.Close()
+	return buffer.Bytes()
+}
+
+func TestDecompression(t *testing.T) {
+	cases := map[string][]string{
+		"gzip":                {"gzip"},
+		"deflate":             {"deflate"},
+		"raw-deflate":         {"raw-deflate"},
+		"br":                  {"br"},
+		"zstd":                {"zstd"},
+		"gzip, br":            {"gzip", "br"},
+		"deflate, zstd, gzip": {"deflate", "zstd", "gzip"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip, deflate, br, zstd" {
+			t.Errorf("Accept-Encoding is wrong. %#v", r.Header.Get("Accept-Encoding"))
+		}
+		encoding := r.URL.Query().Get("encoding")
+		b := []byte("foobar")
+		for _, coding := range cases[encoding] {
+			b = compressForTest(coding, b)
+		}
+		if encoding == "raw-deflate" {
+			encoding = "deflate"
+		}
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(b)
+	}))
+	defer server.Close()
+	for encoding := range cases {
+		req, _ := Get(MakeURL(server.URL, map[string]string{"encoding": encoding}))
+		res, err := req.AcceptCompression().Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, err := res.Text()
+		if err != nil {
+			t.Fatalf("Text() is wrong. %s %v", encoding, err)
+		}
+		if text != "foobar" {
+			t.Fatalf("Text() is wrong. %s %#v", encoding, text)
+		}
+		raw, _ := res.RawContent()
+		if bytes.Equal(raw, []byte("foobar")) {
+			t.Fatalf("RawContent() is wrong. %s %#v", encoding, raw)
+		}
+	}
+}
+
+func TestUnsupportedEncoding(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "compress")
+		w.Write([]byte("foobar"))
+	}))
+	defer server.Close()
+	req, _ := Get(server.URL)
+	res, _ := req.Send()
+	if _, err := res.Content(); err == nil {
+		t.Fatalf("Content() must fail for unsupported encoding.")
+	}
+	raw, _ := res.RawContent()
+	if string(raw) != "foobar" {
+		t.Fatalf("RawContent() is wrong. %#v", raw)
+	}
+}
+
+func TestRequestCompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.Header.Get("Content-Encoding")
+		if r.URL.Path == "/identity" && encoding != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		body, err := newDecodedReader(r.Body, encoding)
+		if err != nil {
+			t.Errorf("Content-Encoding is wrong. %#v", encoding)
+			return
+		}
+		b, _ := ioutil.ReadAll(body)
+		w.Header().Set("X-Encoding", encoding)
+		w.Write(b)
+	}))
+	defer server.Close()
+	data := map[string]string{"foo": strings.Repeat("a", 100)}
+	want := "foo=" + strings.Repeat("a", 100)
+	for _, coding := range []string{"gzip", "deflate", "br", "zstd"} {
+		for _, level := range []int{0, 1} {
+			req, _ := Post(server.URL, data)
+			res, err := req.UseCompression(coding, level).Send()
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, _ := res.Text()
+			if text != want || res.HeaderValue("X-Encoding") != coding {
+				t.Fatalf("compression is wrong. %s %#v", coding, text)
+			}
+		}
+	}
+	// A small body is not compressed.
+	req, _ := Post(server.URL, data)
+	req.UseCompression("br", 0).MinCompressSize = 1000
+	res, _ := req.Send()
+	if res.HeaderValue("X-Encoding") != "" {
+		t.Fatalf("MinCompressSize is wrong. %#v", res.HeaderValue("X-Encoding"))
+	}
+	// The request is sent again without compression on 415.
+	req, _ = Post(server.URL+"/identity", data)
+	res, _ = req.UseCompression("zstd", 0).Send()
+	text, _ := res.Text()
+	if res.StatusCode != http.StatusOK || text != want {
+		t.Fatalf("fallback is wrong. status is %d", res.StatusCode)
+	}
+	req, _ = Post(server.URL, data)
+	if _, err := req.UseCompression("lzma", 0).Send(); err == nil {
+		t.Fatalf("Send() must fail for unsupported compression.")
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	plain := strings.Repeat("a", 1000)
+	compressed := compressForTest("gzip", []byte(plain))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write(compressed)
+			return
+		}
+		io.WriteString(w, plain)
+	}))
+	defer server.Close()
+	tests := []struct {
+		path  string
+		limit BodyLimit
+		kind  string
+	}{
+		{"/", BodyLimit{MaxSize: 100}, "decompressed"},
+		{"/", BodyLimit{MaxCompressedSize: 100}, "compressed"},
+		{"/gzip", BodyLimit{MaxCompressedSize: 10}, "compressed"},
+		{"/gzip", BodyLimit{MaxSize: 100}, "decompressed"},
+		{"/gzip", BodyLimit{MaxRatio: 2}, "ratio"},
+		{"/gzip", BodyLimit{MaxSize: 1000, MaxRatio: 1000}, ""},
+	}
+	for _, test := range tests {
+		session, _ := NewSession()
+		limit := test.limit
+		session.BodyLimit = &limit
+		req, _ := Get(server.URL + test.path)
+		res, err := session.Send(req.AcceptCompression())
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := res.Content()
+		if test.kind == "" {
+			if err != nil || string(b) != plain {
+				t.Fatalf("Content() is wrong. %v", err)
+			}
+			continue
+		}
+		e, ok := err.(*BodyTooLargeError)
+		if !ok || e.Kind != test.kind || int64(len(e.Body)) != e.Limit {
+			t.Fatalf("BodyTooLargeError is wrong. %s %#v", test.path, err)
+		}
+		if _, err = res.Content(); err != e {
+			t.Fatalf("Content() must return the same error. %#v", err)
+		}
+	}
+	// Request.BodyLimit takes precedence over Session.BodyLimit.
+	session, _ := NewSession()
+	session.BodyLimit = &BodyLimit{MaxSize: 10}
+	req, _ := Get(server.URL)
+	res, _ := session.Send(req.SetBodyLimit(&BodyLimit{}))
+	if b, err := res.Content(); err != nil || string(b) != plain {
+		t.Fatalf("Request.BodyLimit is wrong. %v", err)
+	}
+}
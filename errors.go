@@ -0,0 +1,184 @@
+package hrq
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+)
+
+// Errors which a failed request can be compared with by errors.Is.
+var (
+	ErrTimeout           = errors.New("hrq: timeout")
+	ErrDNS               = errors.New("hrq: DNS lookup failed")
+	ErrTLS               = errors.New("hrq: TLS handshake failed")
+	ErrConnectionRefused = errors.New("hrq: connection refused")
+	ErrTooManyRedirects  = errors.New("hrq: too many redirects")
+	// ErrRedirectNotAllowed is returned for a redirect which RedirectPolicy does not allow.
+	ErrRedirectNotAllowed = errors.New("hrq: redirect is not allowed")
+)
+
+// MaxErrorBodySize is the max size of HTTPError.Body.
+var MaxErrorBodySize = 4096
+
+// HTTPError is returned for a response whose status is 4xx or 5xx.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        *url.URL
+	Header     http.Header
+	// Body is the beginning of response body up to MaxErrorBodySize.
+	Body []byte
+	// History is the redirect history.
+	History []*http.Request
+	// Problem is the problem details of response body.
+	// It is nil unless the content-type is problem details.
+	Problem  *Problem
+	Response *Response
+}
+
+func (e *HTTPError) Error() string {
+	message := fmt.Sprintf("hrq: %s %s: %s", e.Method, e.URL, e.Status)
+	if e.Problem != nil && e.Problem.Title != "" {
+		message += ": " + e.Problem.Title
+	}
+	return message
+}
+
+// Unwrap returns the problem details so that errors.As can extract *Problem.
+func (e *HTTPError) Unwrap() error {
+	if e.Problem == nil {
+		return nil
+	}
+	return e.Problem
+}
+
+// RaiseForStatus returns *HTTPError if the status is 4xx or 5xx.
+// Otherwise it returns nil.
+// HTTPError.Problem is set when response body is problem details.
+func (r *Response) RaiseForStatus() error {
+	if r.StatusCode < 400 {
+		return nil
+	}
+	err := &HTTPError{
+		StatusCode: r.StatusCode,
+		Status:     r.Status,
+		Method:     r.Response.Request.Method,
+		URL:        r.URL(),
+		Header:     r.Header,
+		Body:       r.snippet(MaxErrorBodySize),
+		History:    r.History,
+		Response:   r,
+	}
+	if isProblem(r.ContentType()) {
+		err.Problem, _ = r.Problem()
+	}
+	return err
+}
+
+// UseRaiseForStatus makes Send return *HTTPError with the response
+// if the status is 4xx or 5xx.
+func (r *Request) UseRaiseForStatus() *Request {
+	r.RaiseForStatus = true
+	return r
+}
+
+// snippet returns the beginning of response body decompressed by Content-Encoding.
+// The received bytes are put back in front of the body,
+// so the body can still be read by Content.
+func (r *Response) snippet(size int) []byte {
+	b := r.rawBody
+	if b == nil && r.bodyErr == nil {
+		received := r.receivedBody
+		if received == nil {
+			var err error
+			body := r.Body
+			received, err = ioutil.ReadAll(io.LimitReader(body, int64(size)))
+			r.Body = &readCloser{Reader: io.MultiReader(bytes.NewReader(received), body), close: body.Close}
+			if err != nil {
+				return nil
+			}
+		}
+		b = received
+		if encoding := r.HeaderValue("Content-Encoding"); encoding != "" {
+			decoded, err := newDecodedReader(bytes.NewReader(received), encoding)
+			if err != nil {
+				return nil
+			}
+			defer decoded.Close()
+			// The received bytes may be a part of the body, so an unexpected EOF is ignored.
+			b, _ = ioutil.ReadAll(io.LimitReader(decoded, int64(size)))
+		}
+	}
+	if len(b) > size {
+		return b[:size]
+	}
+	return b
+}
+
+// requestError is an error of a request which matches some of the sentinel errors.
+type requestError struct {
+	err   error
+	kinds []error
+}
+
+func (e *requestError) Error() string {
+	return e.err.Error()
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
+}
+
+func (e *requestError) Is(target error) bool {
+	for _, kind := range e.kinds {
+		if kind == target {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyError wraps an error of http.Client to match the sentinel errors.
+func classifyError(err error) error {
+	kinds := []error{}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		kinds = append(kinds, ErrTimeout)
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		kinds = append(kinds, ErrDNS)
+	}
+	if isTLSError(err) {
+		kinds = append(kinds, ErrTLS)
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		kinds = append(kinds, ErrConnectionRefused)
+	}
+	if len(kinds) == 0 {
+		return err
+	}
+	return &requestError{err: err, kinds: kinds}
+}
+
+func isTLSError(err error) bool {
+	var recordErr tls.RecordHeaderError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var verificationErr *tls.CertificateVerificationError
+	var alertErr tls.AlertError
+	return errors.As(err, &recordErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) ||
+		errors.As(err, &verificationErr) || errors.As(err, &alertErr)
+}
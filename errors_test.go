@@ -0,0 +1,129 @@
+package hrq
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRaiseForStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/missing", http.StatusFound)
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "not found")
+		case "/large":
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, strings.Repeat("a", MaxErrorBodySize+1))
+		default:
+			io.WriteString(w, "ok")
+		}
+	}))
+	defer server.Close()
+	req, _ := Get(server.URL)
+	res, _ := req.Send()
+	if err := res.RaiseForStatus(); err != nil {
+		t.Fatalf("RaiseForStatus() must return nil for 200. %v", err)
+	}
+	req, _ = Get(server.URL + "/redirect")
+	res, _ = req.Send()
+	var httpErr *HTTPError
+	if !errors.As(res.RaiseForStatus(), &httpErr) {
+		t.Fatalf("RaiseForStatus() must return *HTTPError for 404.")
+	}
+	if httpErr.StatusCode != 404 || httpErr.Method != "GET" || httpErr.URL.Path != "/missing" ||
+		string(httpErr.Body) != "not found" || len(httpErr.History) != 1 {
+		t.Fatalf("HTTPError is wrong. %#v", httpErr)
+	}
+	if text, _ := res.Text(); text != "not found" {
+		t.Fatalf("text is wrong after RaiseForStatus(). %#v", text)
+	}
+	session, _ := NewSession()
+	session.RaiseForStatus = true
+	req, _ = Get(server.URL + "/large")
+	res, err := session.Send(req)
+	if !errors.As(err, &httpErr) || res == nil || len(httpErr.Body) != MaxErrorBodySize {
+		t.Fatalf("Send() must return *HTTPError. %v", err)
+	}
+	req, _ = Get(server.URL + "/missing")
+	if _, err = req.UseRaiseForStatus().Send(); !errors.As(err, &httpErr) {
+		t.Fatalf("Request.RaiseForStatus is wrong. %v", err)
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			time.Sleep(100 * time.Millisecond)
+			return
+		}
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	}))
+	defer server.Close()
+	req, _ := Get(server.URL)
+	if _, err := req.Send(); !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("error must be ErrTooManyRedirects. %v", err)
+	}
+	req, _ = Get(server.URL + "/slow")
+	req.Timeout = 10 * time.Millisecond
+	if _, err := req.Send(); !errors.Is(err, ErrTimeout) {
+		t.Fatalf("error must be ErrTimeout. %v", err)
+	}
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	defer tlsServer.Close()
+	req, _ = Get(tlsServer.URL)
+	if _, err := req.Send(); !errors.Is(err, ErrTLS) {
+		t.Fatalf("error must be ErrTLS. %v", err)
+	}
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	listener.Close()
+	req, _ = Get("http://" + addr)
+	_, err := req.Send()
+	if !errors.Is(err, ErrConnectionRefused) || errors.Is(err, ErrTimeout) {
+		t.Fatalf("error must be ErrConnectionRefused. %v", err)
+	}
+	var netErr net.Error
+	if !errors.As(err, &netErr) {
+		t.Fatalf("the original error must be kept. %#v", err)
+	}
+	req, _ = Get("http://hrq.invalid")
+	if _, err := req.Send(); !errors.Is(err, ErrDNS) {
+		t.Fatalf("error must be ErrDNS. %v", err)
+	}
+}
+
+func TestRaiseForStatusKeepsBody(t *testing.T) {
+	body := strings.Repeat("a", MaxErrorBodySize+1000)
+	compressed := compressForTest("gzip", []byte(body))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(compressed)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, body)
+	}))
+	defer server.Close()
+	for _, path := range []string{"/", "/gzip"} {
+		req, _ := Get(server.URL + path)
+		res, _ := req.AcceptCompression().Send()
+		var httpErr *HTTPError
+		if !errors.As(res.RaiseForStatus(), &httpErr) || string(httpErr.Body) != body[:MaxErrorBodySize] {
+			t.Fatalf("HTTPError.Body is wrong. %s %d", path, len(httpErr.Body))
+		}
+		text, err := httpErr.Response.Text()
+		if err != nil || text != body {
+			t.Fatalf("body must be readable after RaiseForStatus(). %s %d %v", path, len(text), err)
+		}
+	}
+}
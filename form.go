@@ -0,0 +1,329 @@
+package hrq
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Field is a field of form data.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Fields is form data which keeps the order of fields.
+// The same name can be repeated.
+type Fields []Field
+
+// Add appends a field.
+func (f Fields) Add(name, value string) Fields {
+	return append(f, Field{Name: name, Value: value})
+}
+
+// Encode encodes the fields into "url encoded" form in the order.
+func (f Fields) Encode() string {
+	list := make([]string, len(f))
+	for i, field := range f {
+		list[i] = url.QueryEscape(field.Name) + "=" + url.QueryEscape(field.Value)
+	}
+	return strings.Join(list, "&")
+}
+
+// Values converts the fields to url.Values.
+func (f Fields) Values() url.Values {
+	values := url.Values{}
+	for _, field := range f {
+		values.Add(field.Name, field.Value)
+	}
+	return values
+}
+
+// formFields converts data to Fields.
+// data is map[string]string, url.Values, map[string][]string,
+// map[string]interface{}, Fields or a struct with `form:"name,omitempty"` tags.
+func formFields(data interface{}) (Fields, error) {
+	return dataFields(data, "form")
+}
+
+// dataFields converts data to Fields by the struct tags named tagName.
+func dataFields(data interface{}, tagName string) (Fields, error) {
+	switch d := data.(type) {
+	case nil:
+		return Fields{}, nil
+	case Fields:
+		return d, nil
+	case []Field:
+		return Fields(d), nil
+	case map[string]string:
+		fields := Fields{}
+		for _, k := range sortedKeys(reflect.ValueOf(d)) {
+			fields = fields.Add(k, d[k])
+		}
+		return fields, nil
+	case url.Values:
+		return valuesFields(d), nil
+	case map[string][]string:
+		return valuesFields(d), nil
+	}
+	v := reflect.ValueOf(data)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return Fields{}, nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return structFields(v, tagName, "")
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("%T can not be converted to %s data", data, tagName)
+		}
+		fields := Fields{}
+		for _, k := range sortedKeys(v) {
+			f, err := valueFields(k, v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())), tagName, tagOptions{})
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, f...)
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("%T can not be converted to %s data", data, tagName)
+}
+
+func valuesFields(values map[string][]string) Fields {
+	fields := Fields{}
+	for _, k := range sortedKeys(reflect.ValueOf(values)) {
+		for _, v := range values[k] {
+			fields = fields.Add(k, v)
+		}
+	}
+	return fields
+}
+
+func sortedKeys(m reflect.Value) []string {
+	keys := make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// tagOptions are options of a struct tag like `form:"name,omitempty"`.
+type tagOptions struct {
+	omitempty bool
+	// comma joins the elements of a slice with commas.
+	comma bool
+	// brackets appends "[]" to the name of a slice.
+	brackets bool
+	// unix formats time.Time as unix time.
+	unix bool
+	// int formats bool as 1 or 0.
+	int bool
+}
+
+func parseTag(tag string) (string, tagOptions) {
+	list := strings.Split(tag, ",")
+	options := tagOptions{}
+	for _, o := range list[1:] {
+		switch o {
+		case "omitempty":
+			options.omitempty = true
+		case "comma":
+			options.comma = true
+		case "brackets":
+			options.brackets = true
+		case "unix":
+			options.unix = true
+		case "int":
+			options.int = true
+		}
+	}
+	return list[0], options
+}
+
+// structFields converts a struct to Fields by the tags named tagName.
+// A nested struct is named like "parent[child]",
+// and the fields of an embedded struct are flattened.
+func structFields(v reflect.Value, tagName, prefix string) (Fields, error) {
+	fields := Fields{}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		tag := sf.Tag.Get(tagName)
+		if tag == "-" {
+			continue
+		}
+		name, options := parseTag(tag)
+		fv := v.Field(i)
+		if options.omitempty && isEmptyValue(fv) {
+			continue
+		}
+		if sf.Anonymous && name == "" {
+			ev := fv
+			for ev.Kind() == reflect.Ptr && !ev.IsNil() {
+				ev = ev.Elem()
+			}
+			if ev.Kind() == reflect.Struct && !isText(ev) {
+				f, err := structFields(ev, tagName, prefix)
+				if err != nil {
+					return nil, err
+				}
+				fields = append(fields, f...)
+				continue
+			}
+		}
+		if sf.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if prefix != "" {
+			name = prefix + "[" + name + "]"
+		}
+		f, err := valueFields(name, fv, tagName, options)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f...)
+	}
+	return fields, nil
+}
+
+// valueFields converts a value to Fields named name.
+func valueFields(name string, v reflect.Value, tagName string, options tagOptions) (Fields, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return Fields{}, nil
+		}
+		v = v.Elem()
+	}
+	if isText(v) {
+		s, err := formatValue(v, options)
+		if err != nil {
+			return nil, err
+		}
+		return Fields{{Name: name, Value: s}}, nil
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		return structFields(v, tagName, name)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return Fields{{Name: name, Value: string(v.Bytes())}}, nil
+		}
+		if options.brackets {
+			name += "[]"
+		}
+		list := []string{}
+		fields := Fields{}
+		for i := 0; i < v.Len(); i++ {
+			f, err := valueFields(name, v.Index(i), tagName, options)
+			if err != nil {
+				return nil, err
+			}
+			for _, field := range f {
+				list = append(list, field.Value)
+			}
+			fields = append(fields, f...)
+		}
+		if options.comma {
+			return Fields{{Name: name, Value: strings.Join(list, ",")}}, nil
+		}
+		return fields, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("hrq: unsupported map key type %s", v.Type().Key())
+		}
+		fields := Fields{}
+		for _, k := range sortedKeys(v) {
+			f, err := valueFields(name+"["+k+"]", v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())), tagName, options)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, f...)
+		}
+		return fields, nil
+	}
+	s, err := formatValue(v, options)
+	if err != nil {
+		return nil, err
+	}
+	return Fields{{Name: name, Value: s}}, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+func isText(v reflect.Value) bool {
+	return v.CanInterface() && (v.Type() == timeType || v.Type().Implements(textMarshalerType))
+}
+
+// formatValue formats a scalar value.
+func formatValue(v reflect.Value, options tagOptions) (string, error) {
+	if isText(v) {
+		if t, ok := v.Interface().(time.Time); ok {
+			if options.unix {
+				return strconv.FormatInt(t.Unix(), 10), nil
+			}
+			return t.Format(time.RFC3339), nil
+		}
+		b, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(b), err
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		if options.int {
+			if v.Bool() {
+				return "1", nil
+			}
+			return "0", nil
+		}
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	}
+	return "", fmt.Errorf("hrq: unsupported type %s", v.Type())
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return v.Interface().(time.Time).IsZero()
+		}
+	}
+	return false
+}
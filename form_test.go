@@ -0,0 +1,95 @@
+package hrq
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type formBase struct {
+	ID int `form:"id"`
+}
+
+type formAddress struct {
+	City string `form:"city"`
+	Zip  string `form:"zip,omitempty"`
+}
+
+type formUser struct {
+	formBase
+	Name     string      `form:"name"`
+	Age      int         `form:"age,omitempty"`
+	Score    float64     `form:"score"`
+	Admin    bool        `form:"admin"`
+	Tags     []string    `form:"tag"`
+	Born     time.Time   `form:"born"`
+	Joined   time.Time   `form:"joined,unix"`
+	Address  formAddress `form:"address"`
+	Nickname *string     `form:"nickname"`
+	Secret   string      `form:"-"`
+	Raw      string
+}
+
+func TestFormFields(t *testing.T) {
+	born := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	user := &formUser{
+		formBase: formBase{ID: 1},
+		Name:     "foo",
+		Score:    1.5,
+		Admin:    true,
+		Tags:     []string{"a", "b"},
+		Born:     born,
+		Joined:   born,
+		Address:  formAddress{City: "Tokyo"},
+		Secret:   "secret",
+		Raw:      "raw",
+	}
+	fields, err := formFields(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "id=1&name=foo&score=1.5&admin=true&tag=a&tag=b&born=2000-01-02T03%3A04%3A05Z&joined=946782245&address%5Bcity%5D=Tokyo&Raw=raw"
+	if fields.Encode() != want {
+		t.Fatalf("formFields() is wrong. %#v", fields.Encode())
+	}
+	values := url.Values{"b": {"1", "2"}, "a": {"3"}}
+	fields, _ = formFields(values)
+	if fields.Encode() != "a=3&b=1&b=2" {
+		t.Fatalf("formFields() is wrong. %#v", fields.Encode())
+	}
+	fields, _ = formFields(map[string]interface{}{"n": 1, "l": []int{2, 3}})
+	if fields.Encode() != "l=2&l=3&n=1" {
+		t.Fatalf("formFields() is wrong. %#v", fields.Encode())
+	}
+	if _, err = formFields(1); err == nil {
+		t.Fatalf("formFields() must fail for int.")
+	}
+}
+
+func TestFormFieldsRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") == applicationFormUrlencoded {
+			r.ParseForm()
+		} else {
+			r.ParseMultipartForm(32 << 20)
+		}
+		fmt.Fprintf(w, "%v", r.PostForm["tag"])
+	}))
+	defer server.Close()
+	fields := Fields{}.Add("tag", "b").Add("tag", "a").Add("name", "foo")
+	for _, contentType := range []string{applicationFormUrlencoded, multipartFormData} {
+		req, _ := Post(server.URL, fields)
+		req.SetHeader("Content-Type", contentType)
+		res, err := req.Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, _ := res.Text()
+		if text != "[b a]" {
+			t.Fatalf("text is wrong. text is %#v", text)
+		}
+	}
+}
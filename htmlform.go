@@ -0,0 +1,443 @@
+package hrq
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// FormField is a control of a HTML form.
+type FormField struct {
+	Name string
+	// Type is the type of an input like "text", "hidden", "checkbox", "radio",
+	// "submit" and "file", or "select" and "textarea".
+	// A button is "submit", "reset" or "button".
+	Type  string
+	Value string
+	// Checked is whether a checkbox or a radio button is checked.
+	Checked bool
+	// Options are the values of the options of a select.
+	Options []string
+	// Selected are the selected values of a select.
+	Selected []string
+	// Multiple is whether a select allows multiple values.
+	Multiple bool
+	// Disabled fields are not submitted.
+	Disabled bool
+	// File is the file of a file input set by HTMLForm.SetFile.
+	File *File
+}
+
+// HTMLForm is a form in a HTML response.
+type HTMLForm struct {
+	Name string
+	ID   string
+	// Action is the url resolved by the response url.
+	Action *url.URL
+	// Method is "GET" or "POST".
+	Method string
+	// Enctype is the content-type of a POST request.
+	Enctype string
+	// Fields are the controls in the document order.
+	Fields []*FormField
+}
+
+// Forms returns the forms in a HTML response.
+// A control belongs to the form of its form attribute if it has,
+// or else to the form which is open in the document.
+func (r *Response) Forms() ([]*HTMLForm, error) {
+	doc, err := r.htmlDocument()
+	if err != nil {
+		return nil, err
+	}
+	forms := []*HTMLForm{}
+	controls := []*html.Node{}
+	owners := []*HTMLForm{}
+	var open *HTMLForm
+	// end is the element whose end closes the open form.
+	var end *html.Node
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "form":
+				open = r.newHTMLForm(n)
+				forms = append(forms, open)
+				end = n
+				if n.FirstChild == nil && n.Parent != nil && tableElements[n.Parent.Data] {
+					// In a table, the parser closes a form at once and puts its controls after it.
+					// They belong to the form until the end of the table part.
+					end = n.Parent
+				}
+			case "input", "button", "select", "textarea":
+				controls = append(controls, n)
+				owners = append(owners, open)
+				// The options of a select are read by newFormField.
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n == end {
+			open, end = nil, nil
+		}
+	}
+	walk(doc)
+	for i, n := range controls {
+		form := owners[i]
+		if hasAttribute(n, "form") {
+			form = formByID(forms, attribute(n, "form"))
+		}
+		if form != nil {
+			form.Fields = append(form.Fields, newFormField(n))
+		}
+	}
+	return forms, nil
+}
+
+var tableElements = map[string]bool{"table": true, "tbody": true, "thead": true, "tfoot": true, "tr": true}
+
+func formByID(forms []*HTMLForm, id string) *HTMLForm {
+	for _, form := range forms {
+		if id != "" && form.ID == id {
+			return form
+		}
+	}
+	return nil
+}
+
+// Form returns the form whose name or id is name in a HTML response.
+func (r *Response) Form(name string) (*HTMLForm, error) {
+	forms, err := r.Forms()
+	if err != nil {
+		return nil, err
+	}
+	for _, form := range forms {
+		if form.Name == name || form.ID == name {
+			return form, nil
+		}
+	}
+	return nil, fmt.Errorf("hrq: form %q is not found", name)
+}
+
+// Submit sends the request of a form by the session,
+// so the cookies of the session are sent.
+// See HTMLForm.Request about submit.
+func (s *Session) Submit(form *HTMLForm, submit ...string) (*Response, error) {
+	req, err := form.Request(submit...)
+	if err != nil {
+		return nil, err
+	}
+	return s.Send(req)
+}
+
+func (r *Response) newHTMLForm(n *html.Node) *HTMLForm {
+	form := &HTMLForm{
+		Name:    attribute(n, "name"),
+		ID:      attribute(n, "id"),
+		Action:  r.URL(),
+		Method:  "GET",
+		Enctype: applicationFormUrlencoded,
+	}
+	if action := strings.TrimSpace(attribute(n, "action")); action != "" {
+		if u, err := r.URL().Parse(action); err == nil {
+			form.Action = u
+		}
+	}
+	if strings.EqualFold(attribute(n, "method"), "post") {
+		form.Method = "POST"
+	}
+	if strings.EqualFold(attribute(n, "enctype"), multipartFormData) {
+		form.Enctype = multipartFormData
+	}
+	return form
+}
+
+func newFormField(n *html.Node) *FormField {
+	field := &FormField{
+		Name:     attribute(n, "name"),
+		Value:    attribute(n, "value"),
+		Disabled: hasAttribute(n, "disabled"),
+	}
+	switch n.Data {
+	case "input":
+		field.Type = strings.ToLower(attribute(n, "type"))
+		if field.Type == "" {
+			field.Type = "text"
+		}
+		if (field.Type == "checkbox" || field.Type == "radio") && !hasAttribute(n, "value") {
+			field.Value = "on"
+		}
+		field.Checked = hasAttribute(n, "checked")
+	case "button":
+		field.Type = strings.ToLower(attribute(n, "type"))
+		if field.Type != "reset" && field.Type != "button" {
+			field.Type = "submit"
+		}
+	case "textarea":
+		field.Type = "textarea"
+		field.Value = textContent(n)
+	case "select":
+		field.Type = "select"
+		field.Multiple = hasAttribute(n, "multiple")
+		var first string
+		var walk func(c *html.Node)
+		walk = func(c *html.Node) {
+			if c.Type == html.ElementNode && c.Data == "option" {
+				value := attribute(c, "value")
+				if !hasAttribute(c, "value") {
+					value = strings.Join(strings.Fields(textContent(c)), " ")
+				}
+				if len(field.Options) == 0 {
+					first = value
+				}
+				field.Options = append(field.Options, value)
+				if hasAttribute(c, "selected") && (field.Multiple || len(field.Selected) == 0) {
+					field.Selected = append(field.Selected, value)
+				}
+				return
+			}
+			for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+				walk(cc)
+			}
+		}
+		walk(n)
+		if !field.Multiple && len(field.Selected) == 0 && len(field.Options) > 0 {
+			field.Selected = []string{first}
+		}
+	}
+	return field
+}
+
+// Value returns the value of the first field whose name is name.
+// It is the checked value of checkboxes and radio buttons,
+// and the first selected value of a select.
+func (f *HTMLForm) Value(name string) string {
+	for _, field := range f.Fields {
+		if field.Name != name {
+			continue
+		}
+		switch field.Type {
+		case "checkbox", "radio":
+			if field.Checked {
+				return field.Value
+			}
+		case "select":
+			if len(field.Selected) > 0 {
+				return field.Selected[0]
+			}
+			return ""
+		default:
+			return field.Value
+		}
+	}
+	return ""
+}
+
+// Set sets the values of the fields whose name is name.
+// For checkboxes and radio buttons, the fields of the values are checked
+// and the others are unchecked.
+// For a select, the values must be its options.
+// Otherwise the values are set to the fields in the order.
+func (f *HTMLForm) Set(name string, values ...string) error {
+	fields := []*FormField{}
+	for _, field := range f.Fields {
+		if field.Name == name && !field.Disabled {
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("hrq: form field %q is not found", name)
+	}
+	switch fields[0].Type {
+	case "checkbox", "radio":
+		if fields[0].Type == "radio" && len(values) > 1 {
+			return fmt.Errorf("hrq: radio %q has only one value", name)
+		}
+		for _, value := range values {
+			if !hasValue(fields, value) {
+				return fmt.Errorf("hrq: form field %q has no value %q", name, value)
+			}
+		}
+		for _, field := range fields {
+			field.Checked = contains(values, field.Value)
+		}
+	case "select":
+		field := fields[0]
+		if !field.Multiple && len(values) > 1 {
+			return fmt.Errorf("hrq: select %q has only one value", name)
+		}
+		for _, value := range values {
+			if !contains(field.Options, value) {
+				return fmt.Errorf("hrq: select %q has no option %q", name, value)
+			}
+		}
+		field.Selected = append([]string{}, values...)
+	case "file":
+		return fmt.Errorf("hrq: form field %q is a file input, use SetFile", name)
+	default:
+		if len(values) > len(fields) {
+			return fmt.Errorf("hrq: form field %q has only %d fields", name, len(fields))
+		}
+		for i, value := range values {
+			fields[i].Value = value
+		}
+	}
+	return nil
+}
+
+// SetFile sets a file to the file input whose name is name.
+func (f *HTMLForm) SetFile(name string, file *File) error {
+	for _, field := range f.Fields {
+		if field.Name == name && field.Type == "file" && !field.Disabled {
+			file.FieldName = name
+			field.File = file
+			return nil
+		}
+	}
+	return fmt.Errorf("hrq: file input %q is not found", name)
+}
+
+// Request makes a request which submits the form.
+// submit is the name and optionally the value of the submit button.
+// (default: the first submit button)
+// Fields are sent as the query for GET, and as Request.Data for POST
+// with the content-type of Enctype.
+func (f *HTMLForm) Request(submit ...string) (*Request, error) {
+	button, err := f.submitButton(submit)
+	if err != nil {
+		return nil, err
+	}
+	fields := Fields{}
+	files := []*File{}
+	for _, field := range f.Fields {
+		if field.Name == "" || field.Disabled {
+			continue
+		}
+		switch field.Type {
+		case "checkbox", "radio":
+			if field.Checked {
+				fields = fields.Add(field.Name, field.Value)
+			}
+		case "submit":
+			if field == button {
+				fields = fields.Add(field.Name, field.Value)
+			}
+		case "select":
+			for _, value := range field.Selected {
+				fields = fields.Add(field.Name, value)
+			}
+		case "file":
+			file := field.File
+			if file == nil {
+				file = NewFileBytes("application/octet-stream", field.Name, "", nil)
+			}
+			files = append(files, file)
+		case "reset", "button", "image":
+		default:
+			fields = fields.Add(field.Name, field.Value)
+		}
+	}
+	action := *f.Action
+	action.Fragment = ""
+	if f.Method == "GET" {
+		action.RawQuery = fields.Encode()
+		return Get(action.String())
+	}
+	req, err := Post(action.String(), fields)
+	if err != nil {
+		return nil, err
+	}
+	req.SetHeader("Content-Type", f.Enctype)
+	if f.Enctype == multipartFormData {
+		req.AttachFile(files...)
+	}
+	return req, nil
+}
+
+func (f *HTMLForm) submitButton(submit []string) (*FormField, error) {
+	for _, field := range f.Fields {
+		if field.Type != "submit" || field.Disabled {
+			continue
+		}
+		if len(submit) == 0 {
+			return field, nil
+		}
+		if field.Name == submit[0] && (len(submit) == 1 || field.Value == submit[1]) {
+			return field, nil
+		}
+	}
+	if len(submit) > 0 {
+		return nil, fmt.Errorf("hrq: submit button %q is not found", strings.Join(submit, "="))
+	}
+	return nil, nil
+}
+
+// htmlDocument parses response body converted to UTF-8 as HTML.
+func (r *Response) htmlDocument() (*html.Node, error) {
+	body, err := r.Content()
+	if err != nil {
+		return nil, err
+	}
+	reader, err := charset.NewReader(bytes.NewReader(body), r.ContentType())
+	if err != nil {
+		return nil, err
+	}
+	return html.Parse(reader)
+}
+
+func attribute(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func hasAttribute(n *html.Node, key string) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(c *html.Node)
+	walk = func(c *html.Node) {
+		if c.Type == html.TextNode {
+			b.WriteString(c.Data)
+		}
+		for cc := c.FirstChild; cc != nil; cc = cc.NextSibling {
+			walk(cc)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+func hasValue(fields []*FormField, value string) bool {
+	for _, field := range fields {
+		if field.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,154 @@
+package hrq
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+const formPage = `<html><body>
+<form id="search" action="/search#top">
+  <input name="q" value="go">
+  <input name="user" value="foo" form="login">
+  <input type="submit">
+</form>
+<form name="profile" method="post" action="save">
+  <input type="hidden" name="token" value="abc">
+  <input name="name" value="foo">
+  <input name="disabled" value="x" disabled>
+  <input type="checkbox" name="tag" value="a" checked>
+  <input type="checkbox" name="tag" value="b">
+  <input type="checkbox" name="agree">
+  <input type="radio" name="color" value="red" checked>
+  <input type="radio" name="color" value="blue">
+  <select name="lang"><option>en</option><optgroup><option value="ja">Japanese</option></optgroup></select>
+  <select name="os" multiple><option selected>linux</option><option>mac</option></select>
+  <textarea name="bio">hello</textarea>
+  <input type="reset" name="reset">
+  <button name="action" value="save">Save</button>
+  <button name="action" value="delete">Delete</button>
+</form>
+<form name="upload" method="POST" enctype="multipart/form-data" action="/upload">
+  <input type="file" name="file">
+  <input name="title" value="t">
+</form>
+<table>
+<form id="login" method="post" action="/login">
+<tr><td><input name="password" value="secret"></td></tr>
+</form>
+</table>
+<input name="outside" value="x">
+<input type="checkbox" name="remember" form="login" checked>
+</body></html>`
+
+func TestForms(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, formPage)
+		case "/upload":
+			file, header, _ := r.FormFile("file")
+			b, _ := ioutil.ReadAll(file)
+			fmt.Fprintf(w, "%s %s %s", header.Filename, b, r.FormValue("title"))
+		default:
+			r.ParseForm()
+			cookie, _ := r.Cookie("session")
+			fmt.Fprintf(w, "%s %s %s %s", r.Method, r.URL.Path, cookie, r.Form.Encode())
+		}
+	}))
+	defer server.Close()
+	session, _ := NewSession()
+	req, _ := Get(server.URL)
+	res, _ := session.Send(req)
+	forms, err := res.Forms()
+	if err != nil || len(forms) != 4 {
+		t.Fatalf("Forms() is wrong. %#v %v", forms, err)
+	}
+	search, _ := res.Form("search")
+	if search.Method != "GET" || search.Action.Path != "/search" || search.Enctype != applicationFormUrlencoded {
+		t.Fatalf("form is wrong. %#v", search)
+	}
+	search.Set("q", "hrq")
+	res, _ = session.Submit(search)
+	if text, _ := res.Text(); text != "GET /search session=1 q=hrq" {
+		t.Fatalf("Submit() is wrong. %#v", text)
+	}
+	if _, err = res.Form("profile"); err == nil {
+		t.Fatalf("Form() must fail for a response without the form.")
+	}
+	profile := forms[1]
+	if profile.Value("lang") != "en" || profile.Value("color") != "red" || profile.Value("bio") != "hello" {
+		t.Fatalf("Value() is wrong. %#v", profile.Fields)
+	}
+	req, _ = profile.Request()
+	res, _ = session.Send(req)
+	want := url.Values{
+		"token": {"abc"}, "name": {"foo"}, "tag": {"a"}, "color": {"red"},
+		"lang": {"en"}, "os": {"linux"}, "bio": {"hello"}, "action": {"save"},
+	}
+	if text, _ := res.Text(); text != "POST /save session=1 "+want.Encode() {
+		t.Fatalf("Request() is wrong. %#v", text)
+	}
+	for name, values := range map[string][]string{
+		"name":  {"bar"},
+		"tag":   {"b"},
+		"agree": {"on"},
+		"color": {"blue"},
+		"lang":  {"ja"},
+		"os":    {"linux", "mac"},
+		"bio":   {"bye"},
+	} {
+		if err = profile.Set(name, values...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, _ = session.Submit(profile, "action", "delete")
+	want = url.Values{
+		"token": {"abc"}, "name": {"bar"}, "tag": {"b"}, "agree": {"on"}, "color": {"blue"},
+		"lang": {"ja"}, "os": {"linux", "mac"}, "bio": {"bye"}, "action": {"delete"},
+	}
+	if text, _ := res.Text(); text != "POST /save session=1 "+want.Encode() {
+		t.Fatalf("Submit() is wrong. %#v", text)
+	}
+	for name, values := range map[string][]string{
+		"missing":  {"a"},
+		"disabled": {"a"},
+		"tag":      {"c"},
+		"color":    {"red", "blue"},
+		"lang":     {"fr"},
+	} {
+		if err = profile.Set(name, values...); err == nil {
+			t.Fatalf("Set() must fail. %s %v", name, values)
+		}
+	}
+	if _, err = profile.Request("action", "missing"); err == nil {
+		t.Fatalf("Request() must fail for a missing button.")
+	}
+	upload := forms[2]
+	if err = upload.SetFile("file", NewFileBytes("text/plain", "", "a.txt", []byte("content"))); err != nil {
+		t.Fatal(err)
+	}
+	res, _ = session.Submit(upload)
+	if text, _ := res.Text(); text != "a.txt content t" {
+		t.Fatalf("multipart form is wrong. %#v", text)
+	}
+	login := forms[3]
+	names := []string{}
+	for _, field := range login.Fields {
+		names = append(names, field.Name)
+	}
+	if fmt.Sprint(names) != "[user password remember]" {
+		t.Fatalf("controls of a form in a table or with the form attribute are wrong. %v", names)
+	}
+	res, _ = session.Submit(login)
+	want = url.Values{"user": {"foo"}, "password": {"secret"}, "remember": {"on"}}
+	if text, _ := res.Text(); text != "POST /login session=1 "+want.Encode() {
+		t.Fatalf("Submit() is wrong. %#v", text)
+	}
+}
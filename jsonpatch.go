@@ -0,0 +1,222 @@
+package hrq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const applicationJSONPatch = "application/json-patch+json"
+const applicationMergePatch = "application/merge-patch+json"
+
+// PatchOperation is an operation of JSON Patch (RFC 6902).
+type PatchOperation struct {
+	Op    string
+	Path  string
+	From  string
+	Value interface{}
+}
+
+// MarshalJSON encodes the operation.
+// "value" is written only for add, replace and test, even if it is nil.
+func (o PatchOperation) MarshalJSON() ([]byte, error) {
+	m := map[string]interface{}{
+		"op":   o.Op,
+		"path": o.Path,
+	}
+	switch o.Op {
+	case "add", "replace", "test":
+		m["value"] = o.Value
+	case "move", "copy":
+		m["from"] = o.From
+	}
+	return json.Marshal(m)
+}
+
+// JSONPatch is a JSON Patch document.
+// When it is Request.Data, it is sent as application/json-patch+json.
+type JSONPatch []PatchOperation
+
+// Add appends an add operation.
+func (p JSONPatch) Add(path string, value interface{}) JSONPatch {
+	return append(p, PatchOperation{Op: "add", Path: path, Value: value})
+}
+
+// Remove appends a remove operation.
+func (p JSONPatch) Remove(path string) JSONPatch {
+	return append(p, PatchOperation{Op: "remove", Path: path})
+}
+
+// Replace appends a replace operation.
+func (p JSONPatch) Replace(path string, value interface{}) JSONPatch {
+	return append(p, PatchOperation{Op: "replace", Path: path, Value: value})
+}
+
+// Move appends a move operation.
+func (p JSONPatch) Move(from, path string) JSONPatch {
+	return append(p, PatchOperation{Op: "move", From: from, Path: path})
+}
+
+// Copy appends a copy operation.
+func (p JSONPatch) Copy(from, path string) JSONPatch {
+	return append(p, PatchOperation{Op: "copy", From: from, Path: path})
+}
+
+// Test appends a test operation.
+func (p JSONPatch) Test(path string, value interface{}) JSONPatch {
+	return append(p, PatchOperation{Op: "test", Path: path, Value: value})
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// JSONPointer makes a JSON Pointer (RFC 6901) from reference tokens.
+// JSONPointer("a/b", "c~d") returns "/a~1b/c~0d".
+func JSONPointer(tokens ...string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(pointerEscaper.Replace(token))
+	}
+	return b.String()
+}
+
+// toJSONValue converts v to the value decoded from its JSON.
+func toJSONValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var value interface{}
+	err = decoder.Decode(&value)
+	return value, err
+}
+
+// DiffJSONPatch computes a JSON Patch which changes a into b.
+// a and b are compared by their JSON.
+func DiffJSONPatch(a, b interface{}) (JSONPatch, error) {
+	va, err := toJSONValue(a)
+	if err != nil {
+		return nil, err
+	}
+	vb, err := toJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+	return diffJSON(JSONPatch{}, "", va, vb), nil
+}
+
+func diffJSON(patch JSONPatch, path string, a, b interface{}) JSONPatch {
+	switch va := a.(type) {
+	case map[string]interface{}:
+		vb, ok := b.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for _, k := range sortedKeys(reflect.ValueOf(va)) {
+			if _, ok := vb[k]; !ok {
+				patch = patch.Remove(path + JSONPointer(k))
+			}
+		}
+		for _, k := range sortedKeys(reflect.ValueOf(vb)) {
+			if v, ok := va[k]; ok {
+				patch = diffJSON(patch, path+JSONPointer(k), v, vb[k])
+			} else {
+				patch = patch.Add(path+JSONPointer(k), vb[k])
+			}
+		}
+		return patch
+	case []interface{}:
+		vb, ok := b.([]interface{})
+		if !ok {
+			break
+		}
+		n := len(va)
+		if len(vb) < n {
+			n = len(vb)
+		}
+		for i := 0; i < n; i++ {
+			patch = diffJSON(patch, path+JSONPointer(strconv.Itoa(i)), va[i], vb[i])
+		}
+		for i := len(va) - 1; i >= n; i-- {
+			patch = patch.Remove(path + JSONPointer(strconv.Itoa(i)))
+		}
+		for i := n; i < len(vb); i++ {
+			patch = patch.Add(path+JSONPointer("-"), vb[i])
+		}
+		return patch
+	}
+	if !reflect.DeepEqual(a, b) {
+		patch = patch.Replace(path, b)
+	}
+	return patch
+}
+
+// MergePatch is a JSON Merge Patch document (RFC 7396).
+// When it is Request.Data, it is sent as application/merge-patch+json.
+// A nil value removes the member.
+type MergePatch map[string]interface{}
+
+// DiffMergePatch computes a JSON Merge Patch which changes a into b.
+// a and b must be encoded to JSON objects.
+func DiffMergePatch(a, b interface{}) (MergePatch, error) {
+	va, err := toJSONValue(a)
+	if err != nil {
+		return nil, err
+	}
+	vb, err := toJSONValue(b)
+	if err != nil {
+		return nil, err
+	}
+	ma, ok := va.(map[string]interface{})
+	mb, ok2 := vb.(map[string]interface{})
+	if !ok || !ok2 {
+		return nil, errors.New("hrq: merge patch needs JSON objects")
+	}
+	return diffMerge(ma, mb), nil
+}
+
+func diffMerge(a, b map[string]interface{}) MergePatch {
+	patch := MergePatch{}
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k := range b {
+		va, ok := a[k]
+		if !ok {
+			patch[k] = b[k]
+			continue
+		}
+		ma, ok := va.(map[string]interface{})
+		mb, ok2 := b[k].(map[string]interface{})
+		if ok && ok2 {
+			if p := diffMerge(ma, mb); len(p) > 0 {
+				patch[k] = p
+			}
+			continue
+		}
+		// A null in the new value can not be expressed by a merge patch,
+		// so it removes the member.
+		if !reflect.DeepEqual(va, b[k]) {
+			patch[k] = b[k]
+		}
+	}
+	return patch
+}
+
+// patchContentType returns the content-type of a patch document.
+func patchContentType(data interface{}) string {
+	switch data.(type) {
+	case JSONPatch, *JSONPatch:
+		return applicationJSONPatch
+	case MergePatch, *MergePatch:
+		return applicationMergePatch
+	}
+	return ""
+}
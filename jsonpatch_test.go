@@ -0,0 +1,94 @@
+package hrq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJSONPointer(t *testing.T) {
+	p := JSONPointer("a/b", "c~d", "")
+	if p != "/a~1b/c~0d/" {
+		t.Fatalf("JSONPointer() is wrong. p is %#v", p)
+	}
+	if JSONPointer() != "" {
+		t.Fatalf("JSONPointer() is wrong for the whole document.")
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	patch := JSONPatch{}.
+		Add("/a", nil).
+		Remove("/b").
+		Replace("/c", 1).
+		Move("/d", "/e").
+		Copy("/f", "/g").
+		Test("/h", "x")
+	b, _ := json.Marshal(patch)
+	want := `[{"op":"add","path":"/a","value":null},{"op":"remove","path":"/b"},` +
+		`{"op":"replace","path":"/c","value":1},{"from":"/d","op":"move","path":"/e"},` +
+		`{"from":"/f","op":"copy","path":"/g"},{"op":"test","path":"/h","value":"x"}]`
+	if string(b) != want {
+		t.Fatalf("JSONPatch is wrong. %s", b)
+	}
+}
+
+func TestDiffJSONPatch(t *testing.T) {
+	type user struct {
+		Name string            `json:"name"`
+		Tags []string          `json:"tags"`
+		Meta map[string]string `json:"meta,omitempty"`
+	}
+	a := user{Name: "foo", Tags: []string{"x", "y", "z"}, Meta: map[string]string{"a/b": "1", "c": "2"}}
+	b := user{Name: "bar", Tags: []string{"x", "w"}, Meta: map[string]string{"c": "2", "d": "3"}}
+	patch, err := DiffJSONPatch(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(patch)
+	want := `[{"op":"remove","path":"/meta/a~1b"},{"op":"add","path":"/meta/d","value":"3"},` +
+		`{"op":"replace","path":"/name","value":"bar"},{"op":"replace","path":"/tags/1","value":"w"},` +
+		`{"op":"remove","path":"/tags/2"}]`
+	if string(got) != want {
+		t.Fatalf("DiffJSONPatch() is wrong. %s", got)
+	}
+}
+
+func TestDiffMergePatch(t *testing.T) {
+	a := map[string]interface{}{"a": "b", "c": map[string]interface{}{"d": "e", "f": "g"}, "h": 1}
+	b := map[string]interface{}{"a": "z", "c": map[string]interface{}{"d": "e"}, "h": 1, "i": []int{1}}
+	patch, err := DiffMergePatch(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := json.Marshal(patch)
+	if string(got) != `{"a":"z","c":{"f":null},"i":[1]}` {
+		t.Fatalf("DiffMergePatch() is wrong. %s", got)
+	}
+	if _, err = DiffMergePatch(1, b); err == nil {
+		t.Fatalf("DiffMergePatch() must fail for a number.")
+	}
+}
+
+func TestPatchRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s", r.Header.Get("Content-Type"), b)
+	}))
+	defer server.Close()
+	req, _ := Patch(server.URL, JSONPatch{}.Remove("/a"))
+	res, _ := req.Send()
+	text, _ := res.Text()
+	if text != `application/json-patch+json [{"op":"remove","path":"/a"}]` {
+		t.Fatalf("JSON Patch request is wrong. %#v", text)
+	}
+	req, _ = Patch(server.URL, MergePatch{"a": nil})
+	res, _ = req.Send()
+	text, _ = res.Text()
+	if text != `application/merge-patch+json {"a":null}` {
+		t.Fatalf("JSON Merge Patch request is wrong. %#v", text)
+	}
+}
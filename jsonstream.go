@@ -0,0 +1,224 @@
+package hrq
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// JSONIterator decodes JSON values of response body one by one.
+//
+//	it, err := res.JSONIterator("/items")
+//	defer it.Close()
+//	for it.Next() {
+//		var item Item
+//		if err := it.Decode(&item); err != nil {
+//			return err
+//		}
+//	}
+//	return it.Err()
+type JSONIterator struct {
+	body    io.ReadCloser
+	decoder *json.Decoder
+	decoded bool
+	// array is whether the values are the elements of an array.
+	array bool
+	done  bool
+	err   error
+}
+
+// JSONIterator returns an iterator over the elements of the array
+// which pointer refers in response body.
+// pointer is a JSON Pointer like "/data/items", and "" means the top-level array.
+// The body is streamed, so only the current element is kept in memory.
+func (r *Response) JSONIterator(pointer string) (*JSONIterator, error) {
+	it, err := r.newJSONIterator()
+	if err != nil {
+		return nil, err
+	}
+	if err = it.find(pointer); err != nil {
+		it.Close()
+		return nil, err
+	}
+	return it, nil
+}
+
+// JSONLinesIterator returns an iterator over the values of
+// newline-delimited JSON (NDJSON, JSON Lines) response body.
+func (r *Response) JSONLinesIterator() (*JSONIterator, error) {
+	return r.newJSONIterator()
+}
+
+// JSONElements calls fn for each element of the array which pointer refers.
+// See JSONIterator about pointer.
+func (r *Response) JSONElements(pointer string, fn func(element json.RawMessage) error) error {
+	it, err := r.JSONIterator(pointer)
+	if err != nil {
+		return err
+	}
+	return it.each(fn)
+}
+
+// JSONLines calls fn for each value of newline-delimited JSON response body.
+func (r *Response) JSONLines(fn func(line json.RawMessage) error) error {
+	it, err := r.JSONLinesIterator()
+	if err != nil {
+		return err
+	}
+	return it.each(fn)
+}
+
+func (r *Response) newJSONIterator() (*JSONIterator, error) {
+	body, err := r.Reader()
+	if err != nil {
+		return nil, err
+	}
+	return &JSONIterator{
+		body:    body,
+		decoder: json.NewDecoder(body),
+		decoded: true,
+	}, nil
+}
+
+// Next prepares the next value. It returns false at the end or on an error.
+func (it *JSONIterator) Next() bool {
+	if it.err != nil || it.done {
+		return false
+	}
+	if !it.decoded {
+		// Skip the value which is not decoded.
+		var skip json.RawMessage
+		if it.err = it.decoder.Decode(&skip); it.err != nil {
+			return false
+		}
+	}
+	if !it.decoder.More() {
+		it.end()
+		return false
+	}
+	it.decoded = false
+	return true
+}
+
+// end checks that the values end cleanly,
+// because decoder.More returns false also on a read error.
+func (it *JSONIterator) end() {
+	it.done = true
+	token, err := it.decoder.Token()
+	switch {
+	case err == io.EOF && it.array:
+		it.err = io.ErrUnexpectedEOF
+	case err == io.EOF:
+	case err != nil:
+		it.err = err
+	case !it.array || token != json.Delim(']'):
+		it.err = fmt.Errorf("hrq: unexpected JSON token %v", token)
+	}
+}
+
+// Decode decodes the current value into v.
+func (it *JSONIterator) Decode(v interface{}) error {
+	if it.decoded {
+		return fmt.Errorf("hrq: Decode is called without Next")
+	}
+	it.decoded = true
+	if err := it.decoder.Decode(v); err != nil {
+		it.err = err
+		return err
+	}
+	return nil
+}
+
+// Err returns the error which stopped the iteration.
+func (it *JSONIterator) Err() error {
+	return it.err
+}
+
+// Close closes response body.
+func (it *JSONIterator) Close() error {
+	return it.body.Close()
+}
+
+func (it *JSONIterator) each(fn func(value json.RawMessage) error) error {
+	defer it.Close()
+	for it.Next() {
+		var value json.RawMessage
+		if err := it.Decode(&value); err != nil {
+			return err
+		}
+		if err := fn(value); err != nil {
+			return err
+		}
+	}
+	return it.Err()
+}
+
+// find moves the decoder into the array which pointer refers.
+func (it *JSONIterator) find(pointer string) error {
+	if pointer != "" && !strings.HasPrefix(pointer, "/") {
+		return fmt.Errorf("hrq: invalid JSON pointer %q", pointer)
+	}
+	tokens := []string{}
+	if pointer != "" {
+		tokens = strings.Split(pointer[1:], "/")
+	}
+	for _, token := range tokens {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		found, err := it.findMember(token)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("hrq: JSON pointer %q is not found", pointer)
+		}
+	}
+	delim, err := it.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim != json.Delim('[') {
+		return fmt.Errorf("hrq: JSON pointer %q does not refer an array", pointer)
+	}
+	it.array = true
+	return nil
+}
+
+// findMember skips values until the member of the current object or array.
+func (it *JSONIterator) findMember(token string) (bool, error) {
+	delim, err := it.decoder.Token()
+	if err != nil {
+		return false, err
+	}
+	var skip json.RawMessage
+	switch delim {
+	case json.Delim('{'):
+		for it.decoder.More() {
+			key, err := it.decoder.Token()
+			if err != nil {
+				return false, err
+			}
+			if key == token {
+				return true, nil
+			}
+			if err = it.decoder.Decode(&skip); err != nil {
+				return false, err
+			}
+		}
+	case json.Delim('['):
+		index, err := strconv.Atoi(token)
+		if err != nil || index < 0 {
+			return false, nil
+		}
+		for i := 0; it.decoder.More(); i++ {
+			if i == index {
+				return true, nil
+			}
+			if err = it.decoder.Decode(&skip); err != nil {
+				return false, err
+			}
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,131 @@
+package hrq
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type jsonItem struct {
+	ID int `json:"id"`
+}
+
+func TestJSONIterator(t *testing.T) {
+	bodies := map[string]string{
+		"/array":  `[{"id": 1}, {"id": 2}, {"id": 3}]`,
+		"/object": `{"meta": {"items": [0]}, "data": {"a/b": [{"id": 1}, {"id": 2}, {"id": 3}]}}`,
+		"/lines":  "{\"id\": 1}\n{\"id\": 2}\n\n{\"id\": 3}\n",
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, bodies[r.URL.Path])
+	}))
+	defer server.Close()
+	get := func(path string) *Response {
+		req, _ := Get(server.URL + path)
+		res, err := req.Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	iterators := map[string]func(res *Response) (*JSONIterator, error){
+		"/array": func(res *Response) (*JSONIterator, error) {
+			return res.JSONIterator("")
+		},
+		"/object": func(res *Response) (*JSONIterator, error) {
+			return res.JSONIterator(JSONPointer("data", "a/b"))
+		},
+		"/lines": func(res *Response) (*JSONIterator, error) {
+			return res.JSONLinesIterator()
+		},
+	}
+	for path, iterator := range iterators {
+		it, err := iterator(get(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []int{}
+		for it.Next() {
+			var item jsonItem
+			if err = it.Decode(&item); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, item.ID)
+			// The second element is skipped without Decode.
+			it.Next()
+		}
+		it.Close()
+		if it.Err() != nil || len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+			t.Fatalf("JSONIterator is wrong. %s %v %v", path, ids, it.Err())
+		}
+	}
+	count := 0
+	err := get("/object").JSONElements("/data/a~1b", func(element json.RawMessage) error {
+		count++
+		return nil
+	})
+	if err != nil || count != 3 {
+		t.Fatalf("JSONElements() is wrong. %d %v", count, err)
+	}
+	count = 0
+	err = get("/lines").JSONLines(func(line json.RawMessage) error {
+		count++
+		return nil
+	})
+	if err != nil || count != 3 {
+		t.Fatalf("JSONLines() is wrong. %d %v", count, err)
+	}
+	if _, err = get("/object").JSONIterator("/data/c"); err == nil {
+		t.Fatalf("JSONIterator() must fail for a missing member.")
+	}
+	if _, err = get("/object").JSONIterator("/meta"); err == nil {
+		t.Fatalf("JSONIterator() must fail for an object.")
+	}
+	it, err := get("/object").JSONIterator("/meta/items")
+	if err != nil || !it.Next() {
+		t.Fatalf("JSONIterator() is wrong for a nested array. %v", err)
+	}
+	it.Close()
+}
+
+func TestJSONIteratorError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/lines":
+			// The connection is closed before the rest of the body.
+			w.Header().Set("Content-Length", "100")
+			io.WriteString(w, "{\"id\": 1}\n")
+		case "/array":
+			io.WriteString(w, `{"data": [{"id": 1}, {"id": 2}`)
+		default:
+			io.WriteString(w, "{\"id\":1}\n{\"id\":2}\n")
+		}
+	}))
+	defer server.Close()
+	count := 0
+	countLine := func(line json.RawMessage) error {
+		count++
+		return nil
+	}
+	req, _ := Get(server.URL + "/lines")
+	res, _ := req.Send()
+	if err := res.JSONLines(countLine); !errors.Is(err, io.ErrUnexpectedEOF) || count != 1 {
+		t.Fatalf("JSONLines() must fail for a truncated body. %d %v", count, err)
+	}
+	count = 0
+	req, _ = Get(server.URL + "/array")
+	res, _ = req.Send()
+	if err := res.JSONElements("/data", countLine); err == nil || count != 2 {
+		t.Fatalf("JSONElements() must fail without the end of the array. %d %v", count, err)
+	}
+	count = 0
+	req, _ = Get(server.URL)
+	res, _ = req.SetBodyLimit(&BodyLimit{MaxSize: 8}).Send()
+	err := res.JSONLines(countLine)
+	if _, ok := err.(*BodyTooLargeError); !ok || count != 1 {
+		t.Fatalf("JSONLines() must fail over BodyLimit. %d %v", count, err)
+	}
+}
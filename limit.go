@@ -0,0 +1,114 @@
+package hrq
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// BodyLimit limits the size of a response body read by Response.Content.
+// A zero field means no limit.
+type BodyLimit struct {
+	// MaxCompressedSize is the max size of the body as it is received.
+	MaxCompressedSize int64
+	// MaxSize is the max size of the decompressed body.
+	MaxSize int64
+	// MaxRatio is the max ratio of the decompressed size to the compressed size.
+	// It protects against decompression bombs.
+	MaxRatio float64
+}
+
+// BodyTooLargeError is returned when a response body exceeds BodyLimit.
+type BodyTooLargeError struct {
+	// Kind is "compressed", "decompressed" or "ratio".
+	Kind string
+	// Limit is the number of bytes allowed.
+	Limit int64
+	// Body is the body read until the limit.
+	// It is nil when the body is streamed.
+	Body []byte
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("hrq: response body exceeds the %s limit of %d bytes", e.Kind, e.Limit)
+}
+
+// SetBodyLimit sets the limit of the response body size.
+func (r *Request) SetBodyLimit(limit *BodyLimit) *Request {
+	r.BodyLimit = limit
+	return r
+}
+
+func (r *Request) bodyLimit(session *Session) *BodyLimit {
+	if r.BodyLimit != nil {
+		return r.BodyLimit
+	}
+	return session.BodyLimit
+}
+
+// receivedLimit returns the limit of the received body.
+// Without Content-Encoding, the received body is the decompressed body.
+func (l *BodyLimit) receivedLimit(encoded bool) (string, int64) {
+	if l == nil {
+		return "", 0
+	}
+	kind, limit := "compressed", l.MaxCompressedSize
+	if !encoded && l.MaxSize > 0 && (limit <= 0 || l.MaxSize < limit) {
+		kind, limit = "decompressed", l.MaxSize
+	}
+	return kind, limit
+}
+
+// decodedLimit returns the limit of the body decompressed from compressedSize bytes.
+func (l *BodyLimit) decodedLimit(compressedSize int) (string, int64) {
+	if l == nil {
+		return "", 0
+	}
+	kind, limit := "decompressed", l.MaxSize
+	if l.MaxRatio > 0 {
+		ratioLimit := int64(l.MaxRatio * float64(compressedSize))
+		if limit <= 0 || ratioLimit < limit {
+			kind, limit = "ratio", ratioLimit
+		}
+	}
+	return kind, limit
+}
+
+// readLimited reads r until the limit. (0 or less means no limit)
+func readLimited(r io.Reader, kind string, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	b, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > limit {
+		return nil, &BodyTooLargeError{Kind: kind, Limit: limit, Body: b[:limit]}
+	}
+	return b, nil
+}
+
+// limitReader returns *BodyTooLargeError when more bytes than limit() are read.
+// The error is returned by every later Read.
+type limitReader struct {
+	reader io.Reader
+	read   int64
+	limit  func() (string, int64)
+	err    error
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	n, err := l.reader.Read(p)
+	l.read += int64(n)
+	if kind, limit := l.limit(); limit > 0 && l.read > limit {
+		n -= int(l.read - limit)
+		l.read = limit
+		l.err = &BodyTooLargeError{Kind: kind, Limit: limit}
+		return n, l.err
+	}
+	return n, err
+}
@@ -0,0 +1,137 @@
+package hrq
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultMaxMetaRefreshDelay is the max delay of meta refresh followed
+// without Session.MaxMetaRefreshDelay.
+var DefaultMaxMetaRefreshDelay = 5 * time.Second
+
+var metaRefreshContent = regexp.MustCompile(`(?i)^\s*([0-9.]*)\s*[;,]?\s*(?:url\s*=\s*)?(.*)$`)
+
+// metaRefreshURL returns the target of <meta http-equiv="refresh"> in a HTML response
+// if its delay is not longer than max.
+func (r *Response) metaRefreshURL(max time.Duration) (*url.URL, bool) {
+	mt := mediaType(r.ContentType())
+	if r.StatusCode < 200 || r.StatusCode >= 300 || mt != "text/html" && mt != "application/xhtml+xml" {
+		return nil, false
+	}
+	doc, err := r.htmlDocument()
+	if err != nil {
+		return nil, false
+	}
+	content, ok := findMetaRefresh(doc)
+	if !ok {
+		return nil, false
+	}
+	m := metaRefreshContent.FindStringSubmatch(content)
+	if m[1] != "" {
+		seconds, err := strconv.ParseFloat(m[1], 64)
+		if err != nil || seconds*float64(time.Second) > float64(max) {
+			return nil, false
+		}
+	}
+	target := strings.TrimSpace(m[2])
+	if len(target) >= 2 && (target[0] == '\'' || target[0] == '"') && target[len(target)-1] == target[0] {
+		target = target[1 : len(target)-1]
+	}
+	if target == "" {
+		// A refresh of the page itself is not followed.
+		return nil, false
+	}
+	u, err := r.URL().Parse(target)
+	if err != nil || withoutFragment(u) == withoutFragment(r.URL()) {
+		return nil, false
+	}
+	return u, true
+}
+
+func withoutFragment(u *url.URL) string {
+	v := *u
+	v.Fragment = ""
+	v.RawFragment = ""
+	return v.String()
+}
+
+func findMetaRefresh(n *html.Node) (string, bool) {
+	if n.Type == html.ElementNode && n.Data == "meta" &&
+		strings.EqualFold(strings.TrimSpace(attribute(n, "http-equiv")), "refresh") {
+		return attribute(n, "content"), true
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if content, ok := findMetaRefresh(c); ok {
+			return content, true
+		}
+	}
+	return "", false
+}
+
+// refreshRequest makes a GET request to the meta refresh target
+// which inherits the settings of r.
+func (r *Request) refreshRequest(target *url.URL) *Request {
+	request, _ := http.NewRequest("GET", target.String(), nil)
+	request = request.WithContext(r.Context())
+	for k, v := range r.Header {
+		request.Header[k] = append([]string{}, v...)
+	}
+	for _, key := range bodyHeaders {
+		request.Header.Del(key)
+	}
+	if target.Host != r.URL.Host {
+		for _, key := range authHeaders {
+			request.Header.Del(key)
+		}
+	}
+	request.Header.Del("Cookie")
+	return &Request{
+		Request:        request,
+		Timeout:        r.Timeout,
+		Retry:          r.Retry,
+		BodyLimit:      r.BodyLimit,
+		RaiseForStatus: r.RaiseForStatus,
+		Redirect:       r.Redirect,
+	}
+}
+
+// followMetaRefresh follows meta refresh of HTML responses by handler.
+// They are counted in History and the limit of redirects.
+func (s *Session) followMetaRefresh(handler Handler, r *Request, res *Response) (*Response, error) {
+	policy := r.redirectPolicy(s)
+	max := s.MaxMetaRefreshDelay
+	if max == 0 {
+		max = DefaultMaxMetaRefreshDelay
+	}
+	for {
+		target, ok := res.metaRefreshURL(max)
+		if !ok {
+			return res, nil
+		}
+		history := append(append([]*http.Request{}, res.History...), res.Response.Request)
+		redirects := append(append([]*http.Response{}, res.Redirects...), res.Response)
+		if err := policy.allow(target, history); err == http.ErrUseLastResponse {
+			return res, nil
+		} else if err != nil {
+			return nil, err
+		}
+		r = r.refreshRequest(target)
+		r.refreshed = history
+		s.setDefaults(r)
+		next, err := handler(r)
+		if next != nil {
+			next.History = append(history, next.History...)
+			next.Redirects = append(redirects, next.Redirects...)
+		}
+		if err != nil {
+			return next, err
+		}
+		res = next
+	}
+}
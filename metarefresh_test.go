@@ -0,0 +1,103 @@
+package hrq
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetaRefresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			fmt.Fprint(w, `<html><head><meta http-equiv="Refresh" content="0; URL='/c'"></head></html>`)
+		case "/c":
+			fmt.Fprint(w, `<meta http-equiv="refresh" content="5;url=d">`)
+		case "/self":
+			fmt.Fprint(w, `<meta http-equiv="refresh" content="30">`)
+		case "/dashboard":
+			fmt.Fprint(w, `<meta http-equiv="refresh" content="30;url=/dashboard#top">dashboard`)
+		case "/idle":
+			fmt.Fprint(w, `<meta http-equiv="refresh" content="900;url=/logout">idle`)
+		case "/ping":
+			fmt.Fprint(w, `<meta http-equiv="refresh" content="0;url=/pong">`)
+		case "/pong":
+			fmt.Fprint(w, `<meta http-equiv="refresh" content="0;url=/ping">`)
+		case "/mixed":
+			fmt.Fprint(w, `<meta http-equiv="refresh" content="0;url=/r1">`)
+		case "/r1":
+			http.Redirect(w, r, "/r2", http.StatusFound)
+		case "/r2":
+			http.Redirect(w, r, "/end", http.StatusFound)
+		default:
+			fmt.Fprint(w, "end")
+		}
+	}))
+	defer server.Close()
+	session, _ := NewSession()
+	session.MetaRefresh = true
+	req, _ := Get(server.URL + "/a")
+	res, err := session.Send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := res.Text()
+	if text != "end" || res.URL().Path != "/d" {
+		t.Fatalf("meta refresh is wrong. %#v %s", text, res.URL())
+	}
+	paths := []string{}
+	for _, r := range res.History {
+		paths = append(paths, r.URL.Path)
+	}
+	if fmt.Sprint(paths) != "[/a /b /c]" || len(res.Redirects) != 3 || res.Redirects[1].StatusCode != 200 {
+		t.Fatalf("History is wrong. %v", paths)
+	}
+	req, _ = Get(server.URL + "/self")
+	if res, _ = session.Send(req); res.URL().Path != "/self" {
+		t.Fatalf("refresh of the page itself must not be followed. %s", res.URL())
+	}
+	session.MaxMetaRefreshDelay = time.Hour
+	req, _ = Get(server.URL + "/dashboard")
+	if res, err = session.Send(req); err != nil || res.URL().Path != "/dashboard" {
+		t.Fatalf("refresh to the current url must not be followed. %v", err)
+	}
+	if text, _ = res.Text(); !strings.HasSuffix(text, "dashboard") {
+		t.Fatalf("the refreshing page must be returned. %#v", text)
+	}
+	session.MaxMetaRefreshDelay = 0
+	req, _ = Get(server.URL + "/idle")
+	if res, _ = session.Send(req); res.URL().Path != "/idle" {
+		t.Fatalf("refresh longer than MaxMetaRefreshDelay must not be followed. %s", res.URL())
+	}
+	session.MaxMetaRefreshDelay = time.Hour
+	req, _ = Get(server.URL + "/idle")
+	if res, _ = session.Send(req); res.URL().Path != "/logout" {
+		t.Fatalf("MaxMetaRefreshDelay is wrong. %s", res.URL())
+	}
+	req, _ = Get(server.URL + "/ping")
+	if _, err = session.Send(req.SetRedirect(&RedirectPolicy{MaxRedirects: 3})); !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("meta refresh must be limited. %v", err)
+	}
+	// Meta refresh and HTTP redirects are counted together.
+	req, _ = Get(server.URL + "/mixed")
+	if _, err = session.Send(req.SetRedirect(&RedirectPolicy{MaxRedirects: 2})); !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("meta refresh must be counted by HTTP redirects. %v", err)
+	}
+	req, _ = Get(server.URL + "/mixed")
+	res, err = session.Send(req.SetRedirect(&RedirectPolicy{MaxRedirects: 3}))
+	if err != nil || res.URL().Path != "/end" || len(res.History) != 3 {
+		t.Fatalf("meta refresh and HTTP redirects are wrong. %v", err)
+	}
+	session.MetaRefresh = false
+	req, _ = Get(server.URL + "/b")
+	if res, _ = session.Send(req); res.URL().Path != "/b" {
+		t.Fatalf("meta refresh must be opt-in. %s", res.URL())
+	}
+}
@@ -0,0 +1,251 @@
+package hrq
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// NewFileReader makes a file for multipart/form from io.Reader.
+// If reader is an io.Seeker, it is rewound on redirects and retries.
+// Otherwise it can be sent only once, and it is closed if it is an io.Closer.
+func NewFileReader(contentType, fieldName, fileName string, reader io.Reader) *File {
+	src := readerSource(reader)
+	return &File{
+		ContentType: contentType,
+		FieldName:   fieldName,
+		Name:        fileName,
+		open:        src.open,
+		size:        src.length,
+		once:        src.once,
+	}
+}
+
+// NewFileBytes makes a file for multipart/form from bytes.
+// If contentType is empty, it is detected from the content.
+func NewFileBytes(contentType, fieldName, fileName string, b []byte) *File {
+	if contentType == "" {
+		contentType = http.DetectContentType(b)
+	}
+	src := bytesSource(b)
+	return &File{
+		ContentType: contentType,
+		FieldName:   fieldName,
+		Name:        fileName,
+		open:        src.open,
+		size:        src.length,
+	}
+}
+
+// NewFilePath makes a file for multipart/form from a file path.
+// The file is opened whenever the request is sent.
+// The filename is the base of the path and the content-type is detected
+// from the extension or the content.
+func NewFilePath(fieldName, path string) (*File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType, err = sniffFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &File{
+		ContentType: contentType,
+		FieldName:   fieldName,
+		Name:        filepath.Base(path),
+		open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+		size: info.Size(),
+	}, nil
+}
+
+func sniffFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	b := make([]byte, 512)
+	n, err := io.ReadFull(file, b)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(b[:n]), nil
+}
+
+// SetHeader sets a value of the MIME header of the part.
+func (f *File) SetHeader(key, value string) *File {
+	if f.Header == nil {
+		f.Header = make(textproto.MIMEHeader)
+	}
+	f.Header.Set(key, value)
+	return f
+}
+
+// header returns the MIME header of the part.
+func (f *File) header() textproto.MIMEHeader {
+	part := make(textproto.MIMEHeader)
+	for k, v := range f.Header {
+		part[k] = v
+	}
+	if part.Get("Content-Type") == "" {
+		part.Set("Content-Type", f.ContentType)
+	}
+	if part.Get("Content-Disposition") == "" {
+		desc := fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+			quoteEscaper.Replace(f.FieldName), quoteEscaper.Replace(f.Name))
+		part.Set("Content-Disposition", desc)
+	}
+	return part
+}
+
+// source returns the content of the part.
+// A regular file is read by io.SectionReader,
+// so it can be read again on redirects and retries.
+func (f *File) source() *bodySource {
+	if f.open != nil {
+		return &bodySource{open: f.open, length: f.size, once: f.once}
+	}
+	if f.File == nil {
+		return bytesSource(nil)
+	}
+	if offset, err := f.File.Seek(0, io.SeekCurrent); err == nil {
+		if info, err := f.File.Stat(); err == nil && info.Mode().IsRegular() {
+			size := info.Size() - offset
+			return &bodySource{
+				open: func() (io.ReadCloser, error) {
+					return ioutil.NopCloser(io.NewSectionReader(f.File, offset, size)), nil
+				},
+				length: size,
+			}
+		}
+	}
+	src := readerSource(f.File)
+	src.length = -1
+	src.once = true
+	return src
+}
+
+// multipartSource makes a body which streams the fields and the files.
+// Content-Length is computed up front when all sizes of files are known.
+func multipartSource(fields Fields, files []*File) (src *bodySource, contentType string, err error) {
+	boundary := multipart.NewWriter(ioutil.Discard).Boundary()
+	sources := make([]*bodySource, len(files))
+	src = &bodySource{}
+	known := true
+	for i, file := range files {
+		sources[i] = file.source()
+		src.once = src.once || sources[i].once
+		known = known && sources[i].length >= 0
+	}
+	write := func(w io.Writer, copyFile func(io.Writer, int) error) error {
+		writer := multipart.NewWriter(w)
+		if err := writer.SetBoundary(boundary); err != nil {
+			return err
+		}
+		for _, field := range fields {
+			if err := writer.WriteField(field.Name, field.Value); err != nil {
+				return err
+			}
+		}
+		for i, file := range files {
+			part, err := writer.CreatePart(file.header())
+			if err != nil {
+				return err
+			}
+			if err = copyFile(part, i); err != nil {
+				return err
+			}
+		}
+		return writer.Close()
+	}
+	src.length = -1
+	if known {
+		counter := &countWriter{}
+		err = write(counter, func(w io.Writer, i int) error {
+			counter.n += sources[i].length
+			return nil
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		src.length = counter.n
+	}
+	src.open = func() (io.ReadCloser, error) {
+		return newPipeReader(func(w io.Writer) error {
+			return write(w, func(w io.Writer, i int) error {
+				body, err := sources[i].open()
+				if err != nil {
+					return err
+				}
+				defer body.Close()
+				_, err = io.Copy(w, body)
+				return err
+			})
+		}), nil
+	}
+	contentType = "multipart/form-data; boundary=" + boundary
+	return src, contentType, nil
+}
+
+type countWriter struct {
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// pipeReader reads what write writes through io.Pipe.
+// The goroutine which calls write starts when the reader is read first.
+type pipeReader struct {
+	write  func(io.Writer) error
+	once   sync.Once
+	reader *io.PipeReader
+}
+
+func newPipeReader(write func(io.Writer) error) *pipeReader {
+	return &pipeReader{write: write}
+}
+
+func (p *pipeReader) start() {
+	p.once.Do(func() {
+		reader, writer := io.Pipe()
+		p.reader = reader
+		go func() {
+			writer.CloseWithError(p.write(writer))
+		}()
+	})
+}
+
+func (p *pipeReader) Read(b []byte) (int, error) {
+	p.start()
+	if p.reader == nil {
+		return 0, io.ErrClosedPipe
+	}
+	return p.reader.Read(b)
+}
+
+func (p *pipeReader) Close() error {
+	p.once.Do(func() {})
+	if p.reader == nil {
+		return nil
+	}
+	return p.reader.Close()
+}
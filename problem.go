@@ -0,0 +1,122 @@
+package hrq
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+const applicationProblemJSON = "application/problem+json"
+const applicationProblemXML = "application/problem+xml"
+
+// Problem is a problem details object (RFC 9457, formerly RFC 7807).
+type Problem struct {
+	// Type is a URI reference of the problem type. (default "about:blank")
+	Type     string
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions are the other members.
+	// Values of problem+xml are strings.
+	Extensions map[string]interface{}
+}
+
+func (p *Problem) Error() string {
+	message := "hrq: problem " + p.Type
+	if p.Title != "" {
+		message += ": " + p.Title
+	}
+	if p.Detail != "" {
+		message += ": " + p.Detail
+	}
+	return message
+}
+
+// UnmarshalJSON decodes a problem+json document.
+// A standard member of a wrong type is ignored.
+func (p *Problem) UnmarshalJSON(b []byte) error {
+	members := map[string]interface{}{}
+	if err := json.Unmarshal(b, &members); err != nil {
+		return err
+	}
+	*p = Problem{Type: "about:blank", Extensions: map[string]interface{}{}}
+	for name, value := range members {
+		s, isString := value.(string)
+		switch name {
+		case "type":
+			if isString {
+				p.Type = s
+			}
+		case "title":
+			p.Title = s
+		case "detail":
+			p.Detail = s
+		case "instance":
+			p.Instance = s
+		case "status":
+			if n, ok := value.(float64); ok {
+				p.Status = int(n)
+			}
+		default:
+			p.Extensions[name] = value
+		}
+	}
+	return nil
+}
+
+// UnmarshalXML decodes a problem+xml document.
+func (p *Problem) UnmarshalXML(decoder *xml.Decoder, start xml.StartElement) error {
+	*p = Problem{Type: "about:blank", Extensions: map[string]interface{}{}}
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var value string
+			if err = decoder.DecodeElement(&value, &t); err != nil {
+				return err
+			}
+			value = strings.TrimSpace(value)
+			switch t.Name.Local {
+			case "type":
+				p.Type = value
+			case "title":
+				p.Title = value
+			case "detail":
+				p.Detail = value
+			case "instance":
+				p.Instance = value
+			case "status":
+				p.Status, _ = strconv.Atoi(value)
+			default:
+				p.Extensions[t.Name.Local] = value
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// isProblem returns whether a content-type is problem details.
+func isProblem(contentType string) bool {
+	mt := mediaType(contentType)
+	return mt == applicationProblemJSON || mt == applicationProblemXML
+}
+
+// Problem decodes response body as problem details.
+// It returns nil without an error
+// unless the content-type is application/problem+json or application/problem+xml.
+func (r *Response) Problem() (*Problem, error) {
+	if !isProblem(r.ContentType()) {
+		return nil, nil
+	}
+	problem := &Problem{}
+	if err := r.Decode(problem); err != nil {
+		return nil, err
+	}
+	return problem, nil
+}
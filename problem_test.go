@@ -0,0 +1,95 @@
+package hrq
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProblem(t *testing.T) {
+	bodies := map[string]string{
+		applicationProblemJSON: `{"type": "https://example.com/probs/out-of-credit", "title": "You do not have enough credit.",
+			"status": 403, "detail": "Your current balance is 30.", "instance": "/account/12345/msgs/abc",
+			"balance": 30, "accounts": ["/account/12345"]}`,
+		applicationProblemXML: `<?xml version="1.0" encoding="UTF-8"?>
+<problem xmlns="urn:ietf:rfc:7807">
+  <type>https://example.com/probs/out-of-credit</type>
+  <title>You do not have enough credit.</title>
+  <status>403</status>
+  <detail>Your current balance is 30.</detail>
+  <instance>/account/12345/msgs/abc</instance>
+  <balance>30</balance>
+</problem>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.URL.Query().Get("type")
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, bodies[contentType])
+	}))
+	defer server.Close()
+	for contentType := range bodies {
+		session, _ := NewSession()
+		session.RaiseForStatus = true
+		req, _ := Get(server.URL+"{?type}", Vars{"type": contentType})
+		_, err := session.Send(req)
+		var problem *Problem
+		if !errors.As(err, &problem) {
+			t.Fatalf("error must have *Problem. %s %v", contentType, err)
+		}
+		if problem.Type != "https://example.com/probs/out-of-credit" || problem.Title != "You do not have enough credit." ||
+			problem.Status != 403 || problem.Detail != "Your current balance is 30." || problem.Instance != "/account/12345/msgs/abc" {
+			t.Fatalf("Problem is wrong. %#v", problem)
+		}
+		if contentType == applicationProblemJSON && problem.Extensions["balance"] != float64(30) {
+			t.Fatalf("Extensions are wrong. %#v", problem.Extensions)
+		}
+		if contentType == applicationProblemXML && problem.Extensions["balance"] != "30" {
+			t.Fatalf("Extensions are wrong. %#v", problem.Extensions)
+		}
+	}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", applicationProblemJSON)
+		io.WriteString(w, `{"title": 1}`)
+	}))
+	defer server.Close()
+	req, _ := Get(server.URL)
+	res, _ := req.Send()
+	problem, err := res.Problem()
+	if err != nil || problem.Type != "about:blank" || problem.Title != "" {
+		t.Fatalf("Problem() is wrong. %#v %v", problem, err)
+	}
+	res.Header.Set("Content-Type", "text/plain")
+	if problem, _ = res.Problem(); problem != nil {
+		t.Fatalf("Problem() must return nil for text/plain.")
+	}
+}
+
+func TestLargeProblem(t *testing.T) {
+	detail := strings.Repeat("a", MaxErrorBodySize)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", applicationProblemJSON)
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"title": "Invalid", "status": 400, "detail": %q}`, detail)
+	}))
+	defer server.Close()
+	req, _ := Get(server.URL)
+	_, err := req.UseRaiseForStatus().Send()
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Problem == nil {
+		t.Fatalf("HTTPError.Problem must be set for a large body. %v", err)
+	}
+	if httpErr.Problem.Title != "Invalid" || httpErr.Problem.Detail != detail || len(httpErr.Body) != MaxErrorBodySize {
+		t.Fatalf("Problem is wrong. %#v", httpErr.Problem.Title)
+	}
+	// BodyLimit is applied to the problem details.
+	req, _ = Get(server.URL)
+	_, err = req.UseRaiseForStatus().SetBodyLimit(&BodyLimit{MaxSize: 100}).Send()
+	if !errors.As(err, &httpErr) || httpErr.Problem != nil {
+		t.Fatalf("Problem must not be decoded over BodyLimit. %v", err)
+	}
+}
@@ -0,0 +1,125 @@
+package hrq
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// DefaultMaxRedirects is the max number of redirects followed without RedirectPolicy.MaxRedirects.
+var DefaultMaxRedirects = 10
+
+// RedirectPolicy decides whether and how redirects are followed.
+type RedirectPolicy struct {
+	// MaxRedirects is the max number of redirects followed.
+	// A response which redirects more than it fails with ErrTooManyRedirects.
+	// (0 means DefaultMaxRedirects)
+	MaxRedirects int
+	// Disable makes Send return the redirect response without following it.
+	Disable bool
+	// SameHost allows only redirects to the host of the first request.
+	SameHost bool
+	// SameScheme allows only redirects to the scheme of the first request.
+	SameScheme bool
+	// KeepAuth keeps Authorization and Proxy-Authorization headers
+	// on redirects to other hosts.
+	// (default false: they are removed like http.Client unless the host is a subdomain)
+	KeepAuth bool
+	// StripAuth removes them on redirects to any other host including subdomains.
+	StripAuth bool
+	// PreserveMethod keeps the method and the body on 301 and 302.
+	// (default false: POST and so on are changed to GET)
+	PreserveMethod bool
+}
+
+var authHeaders = []string{"Authorization", "Proxy-Authorization"}
+
+var bodyHeaders = []string{"Content-Type", "Content-Encoding", "Content-Language", "Content-Location"}
+
+// SetRedirect sets a redirect policy.
+func (r *Request) SetRedirect(policy *RedirectPolicy) *Request {
+	r.Redirect = policy
+	return r
+}
+
+func (r *Request) redirectPolicy(session *Session) *RedirectPolicy {
+	if r.Redirect != nil {
+		return r.Redirect
+	}
+	return session.Redirect
+}
+
+// allow returns nil if a redirect to target after via is allowed,
+// or http.ErrUseLastResponse if redirects are disabled.
+// via includes the first request, so len(via)-1 redirects are already followed.
+func (p *RedirectPolicy) allow(target *url.URL, via []*http.Request) error {
+	max := DefaultMaxRedirects
+	if p != nil {
+		if p.Disable {
+			return http.ErrUseLastResponse
+		}
+		if p.MaxRedirects > 0 {
+			max = p.MaxRedirects
+		}
+	}
+	if len(via) > max {
+		return ErrTooManyRedirects
+	}
+	if p == nil {
+		return nil
+	}
+	first := via[0].URL
+	if p.SameHost && target.Host != first.Host || p.SameScheme && target.Scheme != first.Scheme {
+		return ErrRedirectNotAllowed
+	}
+	return nil
+}
+
+// check returns nil to follow the redirect to req,
+// http.ErrUseLastResponse to return the redirect response, or an error.
+func (p *RedirectPolicy) check(req *http.Request, via []*http.Request) error {
+	if err := p.allow(req.URL, via); err != nil || p == nil {
+		return err
+	}
+	first, prev := via[0], via[len(via)-1]
+	if p.KeepAuth {
+		for _, key := range authHeaders {
+			if _, ok := req.Header[key]; !ok && first.Header[key] != nil {
+				req.Header[key] = first.Header[key]
+			}
+		}
+	}
+	if p.StripAuth && req.URL.Host != first.URL.Host {
+		for _, key := range authHeaders {
+			req.Header.Del(key)
+		}
+	}
+	if p.PreserveMethod && req.Response.StatusCode != http.StatusSeeOther {
+		return preserveMethod(req, prev)
+	}
+	return nil
+}
+
+// preserveMethod sends req by the method and the body of prev.
+func preserveMethod(req, prev *http.Request) error {
+	req.Method = prev.Method
+	if prev.Body == nil || prev.Body == http.NoBody || req.Body != nil && req.Body != http.NoBody {
+		return nil
+	}
+	if prev.GetBody == nil {
+		// The body can not be sent again.
+		return http.ErrUseLastResponse
+	}
+	body, err := prev.GetBody()
+	if err != nil {
+		return err
+	}
+	req.Body = body
+	req.GetBody = prev.GetBody
+	req.ContentLength = prev.ContentLength
+	for _, key := range bodyHeaders {
+		if values, ok := prev.Header[key]; ok {
+			req.Header[key] = values
+		}
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package hrq
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedirectPolicy(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.SetCookie(w, &http.Cookie{Name: "foo", Value: "bar"})
+			http.Redirect(w, r, "/b", http.StatusMovedPermanently)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		case "/other":
+			http.Redirect(w, r, other.URL, http.StatusFound)
+		case "/https":
+			http.Redirect(w, r, strings.Replace(other.URL, "http:", "https:", 1), http.StatusFound)
+		default:
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer server.Close()
+	send := func(req *Request, policy *RedirectPolicy) (*Response, error) {
+		session, _ := NewSession()
+		session.Redirect = policy
+		return session.Send(req)
+	}
+	req, _ := Get(server.URL + "/a")
+	res, err := send(req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Redirects) != 2 || len(res.History) != 2 {
+		t.Fatalf("Redirects are wrong. %#v", res.Redirects)
+	}
+	first := res.Redirects[0]
+	if first.StatusCode != 301 || first.Header.Get("Location") != "/b" || first.Cookies()[0].Value != "bar" ||
+		res.Redirects[1].StatusCode != 302 || res.Redirects[1].Request.URL.Path != "/b" {
+		t.Fatalf("Redirects are wrong. %#v", first)
+	}
+	req, _ = Get(server.URL + "/a")
+	if _, err = send(req, &RedirectPolicy{MaxRedirects: 1}); !errors.Is(err, ErrTooManyRedirects) {
+		t.Fatalf("MaxRedirects is wrong. %v", err)
+	}
+	req, _ = Get(server.URL + "/a")
+	if res, err = send(req, &RedirectPolicy{MaxRedirects: 2}); err != nil || res.URL().Path != "/c" {
+		t.Fatalf("redirects up to MaxRedirects must be followed. %v", err)
+	}
+	req, _ = Get(server.URL + "/b")
+	if res, err = send(req, &RedirectPolicy{MaxRedirects: 1}); err != nil || res.URL().Path != "/c" {
+		t.Fatalf("MaxRedirects: 1 must follow a redirect. %v", err)
+	}
+	req, _ = Get(server.URL + "/a")
+	res, err = send(req, &RedirectPolicy{Disable: true})
+	if err != nil || res.StatusCode != 301 || len(res.Redirects) != 0 {
+		t.Fatalf("Disable is wrong. %v", err)
+	}
+	req, _ = Get(server.URL + "/other")
+	if _, err = send(req, &RedirectPolicy{SameHost: true}); !errors.Is(err, ErrRedirectNotAllowed) {
+		t.Fatalf("SameHost is wrong. %v", err)
+	}
+	req, _ = Get(server.URL + "/https")
+	if _, err = send(req, &RedirectPolicy{SameScheme: true}); !errors.Is(err, ErrRedirectNotAllowed) {
+		t.Fatalf("SameScheme is wrong. %v", err)
+	}
+	// Request.Redirect takes precedence over Session.Redirect.
+	req, _ = Get(server.URL + "/a")
+	if _, err = send(req.SetRedirect(&RedirectPolicy{}), &RedirectPolicy{Disable: true}); err != nil {
+		t.Fatalf("Request.Redirect is wrong. %v", err)
+	}
+}
+
+func TestRedirectAuth(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.Header.Get("Authorization"))
+	}))
+	defer other.Close()
+	targets := map[string]string{
+		"/localhost": strings.Replace(other.URL, "127.0.0.1", "localhost", 1),
+		"/port":      other.URL,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, targets[r.URL.Path], http.StatusFound)
+	}))
+	defer server.Close()
+	tests := []struct {
+		path   string
+		policy *RedirectPolicy
+		want   string
+	}{
+		{"/localhost", nil, ""},
+		{"/localhost", &RedirectPolicy{KeepAuth: true}, "secret"},
+		{"/port", nil, "secret"},
+		{"/port", &RedirectPolicy{StripAuth: true}, ""},
+	}
+	for _, test := range tests {
+		req, _ := Get(server.URL + test.path)
+		req.SetHeader("Authorization", "secret")
+		res, err := req.SetRedirect(test.policy).Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text, _ := res.Text(); text != test.want {
+			t.Fatalf("Authorization is wrong. %s %#v %#v", test.path, test.policy, text)
+		}
+	}
+}
+
+func TestRedirectPreserveMethod(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/301":
+			http.Redirect(w, r, "/307", http.StatusMovedPermanently)
+		case "/307":
+			http.Redirect(w, r, "/end", http.StatusTemporaryRedirect)
+		case "/303":
+			http.Redirect(w, r, "/end", http.StatusSeeOther)
+		default:
+			b, _ := ioutil.ReadAll(r.Body)
+			fmt.Fprintf(w, "%s %s %s", r.Method, r.Header.Get("Content-Type"), b)
+		}
+	}))
+	defer server.Close()
+	tests := []struct {
+		path   string
+		policy *RedirectPolicy
+		want   string
+	}{
+		{"/301", nil, "GET  "},
+		{"/301", &RedirectPolicy{PreserveMethod: true}, "POST application/x-www-form-urlencoded foo=bar"},
+		{"/303", &RedirectPolicy{PreserveMethod: true}, "GET  "},
+	}
+	for _, test := range tests {
+		req, _ := Post(server.URL+test.path, map[string]string{"foo": "bar"})
+		res, err := req.SetRedirect(test.policy).Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text, _ := res.Text(); text != test.want {
+			t.Fatalf("redirect is wrong. %s %#v", test.path, text)
+		}
+	}
+}
@@ -1,19 +1,11 @@
 package hrq
 
 import (
-	"bytes"
-	"compress/gzip"
 	"context"
-	"encoding/json"
-	"errors"
-	"fmt"
 	"io"
-	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"net/http/httputil"
 	"net/textproto"
-	"net/url"
 	"os"
 	"time"
 )
@@ -21,6 +13,7 @@ import (
 const applicationFormUrlencoded = "application/x-www-form-urlencoded"
 const applicationJSON = "application/json"
 const multipartFormData = "multipart/form-data"
+const applicationXML = "application/xml"
 
 // DefaultTimeout is seconds of timeout.
 var DefaultTimeout = 15
@@ -29,83 +22,174 @@ var DefaultTimeout = 15
 var DefaultContentType = applicationFormUrlencoded
 
 func send(session *Session, r *Request) (res *Response, err error) {
-	if r.isPostOrPut() && r.Data != nil && r.HeaderValue("Content-Type") != multipartFormData {
-		if r.contentType() == applicationFormUrlencoded {
-			data, ok := r.Data.(map[string]string)
-			if !ok {
-				err := errors.New("data is not a map[string]string at Request.Send()")
-				return nil, err
-			}
-			mapStringList := mapStringList(data)
-			values := []byte(url.Values(mapStringList).Encode())
-			r.setBody(values)
-		} else if r.contentType() == applicationJSON {
-			jsonBytes, err := json.Marshal(r.Data)
-			if err != nil {
+	res, err = sendWithRetry(session, r)
+	if err == nil && (r.RaiseForStatus || session.RaiseForStatus) {
+		err = res.RaiseForStatus()
+	}
+	return
+}
+
+// sendWithRetry sends a request until the retry policy stops it.
+func sendWithRetry(session *Session, r *Request) (res *Response, err error) {
+	r.identity = false
+	err = r.encodeBody()
+	defer r.closeFiles()
+	if err != nil {
+		return
+	}
+	policy := r.retryPolicy(session)
+	for attempt := 1; ; attempt++ {
+		res, err = sendOnce(session, r)
+		if err == nil && res.StatusCode == http.StatusUnsupportedMediaType && r.contentCoding != "" && r.GetBody != nil {
+			// The server does not accept the compressed body.
+			res.discard()
+			if err = r.disableCompression(); err != nil {
 				return nil, err
 			}
-			r.setBody(jsonBytes)
+			res, err = sendOnce(session, r)
+		}
+		if res != nil {
+			res.Attempts = attempt
+		}
+		delay, ok := policy.next(attempt, r, res, err)
+		if !ok || !r.rewindable() {
+			return
 		}
-	} else if r.isPostOrPut() && r.contentType() == multipartFormData {
-		var buffer bytes.Buffer
-		writer := multipart.NewWriter(&buffer)
-		data, ok := r.Data.(map[string]string)
-		if !ok {
-			err := errors.New("data is not a map[string]string at Request.Send()")
+		if res != nil {
+			res.discard()
+		}
+		if err = wait(r.Context(), delay); err != nil {
 			return nil, err
 		}
-		for k, v := range data {
-			writer.WriteField(k, v)
+		if err = r.rewindBody(); err != nil {
+			return nil, err
 		}
-		for _, file := range r.Files {
-			part := make(textproto.MIMEHeader)
-			part.Set("Content-Type", file.ContentType)
-			desc := fmt.Sprintf(`form-data; name="%s"; filename="%s"`, file.FieldName, file.Name)
-			part.Set("Content-Disposition", desc)
-			fileWriter, err := writer.CreatePart(part)
-			if err != nil {
-				return nil, err
-			}
-			_, err = io.Copy(fileWriter, file.File)
-			if err != nil {
-				return nil, err
-			}
-			defer file.File.Close()
+	}
+}
+
+// encodeBody encodes Request.Data into the request body.
+func (r *Request) encodeBody() error {
+	if reader, ok := r.Data.(io.Reader); ok {
+		if r.source == nil || r.source.reader != reader {
+			r.source = readerSource(reader)
 		}
-		writer.Close()
-		r.SetHeader("Content-Type", writer.FormDataContentType())
-		r.ContentLength = int64(buffer.Len())
-		b := buffer.Bytes()
-		r.setBody(b)
+		return r.setSource(r.source)
 	}
-	requestHistory := []*http.Request{}
-	session.CheckRedirect = func(req *http.Request, via []*http.Request) error {
-		requestHistory = via
-		if len(via) >= 10 {
-			return errors.New("there are 10 redirects")
+	if r.Data == nil && r.source != nil {
+		return r.setSource(r.source)
+	}
+	if contentType := patchContentType(r.Data); contentType != "" && mediaType(r.contentType()) != contentType {
+		r.SetHeader("Content-Type", contentType)
+	}
+	if r.Data != nil && r.contentType() == "" {
+		r.SetHeader("Content-Type", DefaultContentType)
+	}
+	mt := mediaType(r.contentType())
+	if mt == multipartFormData {
+		fields, err := formFields(r.Data)
+		if err != nil {
+			return err
 		}
+		src, contentType, err := multipartSource(fields, r.Files)
+		if err != nil {
+			return err
+		}
+		r.SetHeader("Content-Type", contentType)
+		return r.setSource(src)
+	}
+	if r.Data == nil {
 		return nil
 	}
-	if r.Gzip {
-		r.SetHeader("Content-Encoding", "gzip")
+	switch data := r.Data.(type) {
+	case []byte:
+		return r.setBody(data)
+	case string:
+		return r.setBody([]byte(data))
+	}
+	if mt == applicationFormUrlencoded {
+		fields, err := formFields(r.Data)
+		if err != nil {
+			return err
+		}
+		return r.setBody([]byte(fields.Encode()))
+	}
+	codec := LookupCodec(r.contentType())
+	if codec == nil {
+		return noCodecError(r.contentType())
+	}
+	b, err := codec.Marshal(r.Data)
+	if err != nil {
+		return err
+	}
+	return r.setBody(b)
+}
+
+// sendOnce sends a request once.
+func sendOnce(session *Session, r *Request) (res *Response, err error) {
+	// The shared client is copied so that the redirect policy of this request
+	// never leaks into other goroutines using the same session.
+	requestHistory := []*http.Request{}
+	redirects := []*http.Response{}
+	client := *session.Client
+	checkRedirect := session.CheckRedirect
+	policy := r.redirectPolicy(session)
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		// The requests followed by meta refresh before are counted by the policy.
+		counted := append(append([]*http.Request{}, r.refreshed...), via...)
+		var err error
+		if checkRedirect != nil && policy == nil {
+			err = checkRedirect(req, via)
+		} else if err = policy.check(req, counted); err == nil && checkRedirect != nil {
+			err = checkRedirect(req, via)
+		}
+		if err == nil {
+			requestHistory = via
+			redirects = append(redirects, req.Response)
+		}
+		return err
 	}
-	response, err := session.Do(r.Request)
+	req, cancel := r.withTimeout()
+	response, err := client.Do(req)
 	if err != nil {
+		cancel()
+		err = classifyError(err)
 		return
 	}
+	response.Body = &cancelBody{ReadCloser: response.Body, cancel: cancel}
 	res = &Response{
-		Response: response,
-		History:  requestHistory,
+		Response:  response,
+		History:   requestHistory,
+		Redirects: redirects,
+		limit:     r.bodyLimit(session),
 	}
 	return
 }
 
+// cancelBody releases the context of a request when its response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 // File is file for multipart/form.
 type File struct {
 	ContentType string
 	FieldName   string
 	Name        string
 	File        *os.File
+	// Header is additional MIME header of the part
+	// like Content-Transfer-Encoding.
+	Header textproto.MIMEHeader
+	// open opens the content instead of File.
+	open func() (io.ReadCloser, error)
+	size int64
+	once bool
 }
 
 // Request inherits http.Request.
@@ -117,30 +201,79 @@ type Request struct {
 	// Gzip is a flag to decide whether to compress by gzip or not.
 	// (defaut false)
 	Gzip bool
+	// Retry is the retry policy of this request.
+	// It takes precedence over Session.Retry.
+	Retry *RetryPolicy
+	// Compression is the content-coding to compress the request body
+	// ("gzip", "deflate", "br" or "zstd").
+	// It takes precedence over Gzip.
+	Compression string
+	// CompressionLevel is the level of Compression.
+	// (0 means the default level)
+	CompressionLevel int
+	// MinCompressSize is the size under which the request body is not compressed.
+	// A body whose size is unknown is always compressed.
+	MinCompressSize int64
+	// BodyLimit limits the size of the response body.
+	// It takes precedence over Session.BodyLimit.
+	BodyLimit *BodyLimit
+	// RaiseForStatus makes Send return *HTTPError if the status is 4xx or 5xx.
+	RaiseForStatus bool
+	// Redirect is the redirect policy of this request.
+	// It takes precedence over Session.Redirect.
+	Redirect *RedirectPolicy
+	// refreshed is the requests before this request followed by meta refresh.
+	// They are counted against the redirect limit.
+	refreshed     []*http.Request
+	source        *bodySource
+	body          *bodySource
+	contentCoding string
+	identity      bool
 }
 
 func (r *Request) contentType() string {
 	return r.HeaderValue("Content-Type")
 }
 
-func (r *Request) isPostOrPut() bool {
-	return r.Method == "POST" || r.Method == "PUT"
+// withTimeout returns a shallow copy of the http.Request
+// whose context has the deadline of Request.Timeout.
+func (r *Request) withTimeout() (*http.Request, context.CancelFunc) {
+	if r.Timeout <= 0 {
+		return r.Request, func() {}
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), r.Timeout)
+	return r.Request.WithContext(ctx), cancel
+}
+
+func (r *Request) setBody(b []byte) error {
+	return r.setSource(bytesSource(b))
 }
 
-func (r *Request) setBody(b []byte) {
-	if r.Gzip {
-		var buffer bytes.Buffer
-		writer := gzip.NewWriter(&buffer)
-		writer.Write(b)
-		writer.Close()
-		b = buffer.Bytes()
+// closeFiles closes files added by AddFile.
+func (r *Request) closeFiles() {
+	for _, file := range r.Files {
+		if file.File != nil {
+			file.File.Close()
+		}
 	}
-	r.GetBody = func() (io.ReadCloser, error) {
-		reader := bytes.NewReader(b)
-		return ioutil.NopCloser(reader), nil
+}
+
+// rewindable reports whether the request body can be sent again.
+func (r *Request) rewindable() bool {
+	return r.Body == nil || r.Body == http.NoBody || r.GetBody != nil
+}
+
+// rewindBody replaces the consumed request body with a new one.
+func (r *Request) rewindBody() error {
+	if r.GetBody == nil {
+		return nil
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return err
 	}
-	reader := bytes.NewReader(b)
-	r.Body = ioutil.NopCloser(reader)
+	r.Body = body
+	return nil
 }
 
 // AcceptGzip is an alias of req.SetHeader("Accept-Encoding", "gzip").
@@ -149,12 +282,33 @@ func (r *Request) AcceptGzip() *Request {
 	return r.SetHeader("Accept-Encoding", "gzip")
 }
 
+// AcceptCompression is an alias of req.SetHeader("Accept-Encoding", "gzip, deflate, br, zstd").
+// hrq automatically decompress response body.
+func (r *Request) AcceptCompression() *Request {
+	return r.SetHeader("Accept-Encoding", acceptEncoding)
+}
+
 // UseGzip makes Request.Gzip to true.
 func (r *Request) UseGzip() *Request {
 	r.Gzip = true
 	return r
 }
 
+// UseCompression sets the content-coding and the level to compress the request body.
+// If the server responds 415 Unsupported Media Type,
+// the request is sent again without compression.
+func (r *Request) UseCompression(coding string, level int) *Request {
+	r.Compression = coding
+	r.CompressionLevel = level
+	return r
+}
+
+// SetRetry sets a retry policy.
+func (r *Request) SetRetry(policy *RetryPolicy) *Request {
+	r.Retry = policy
+	return r
+}
+
 // WithContext sets a context.
 func (r *Request) WithContext(ctx context.Context) *Request {
 	r.Request = r.Request.WithContext(ctx)
@@ -173,6 +327,12 @@ func (r *Request) AddFile(contentType, fieldName, fileName string, file *os.File
 	return r
 }
 
+// AttachFile sets files made by NewFileReader, NewFileBytes or NewFilePath for multipart/form.
+func (r *Request) AttachFile(files ...*File) *Request {
+	r.Files = append(r.Files, files...)
+	return r
+}
+
 // SetTimeout sets timeout.
 func (r *Request) SetTimeout(timeout int) *Request {
 	r.Timeout = time.Duration(timeout) * time.Second
@@ -189,22 +349,29 @@ func (r *Request) SetApplicationJSON() *Request {
 	return r.SetHeader("Content-Type", applicationJSON)
 }
 
+// SetApplicationXML is an alias of req.SetHeader("Content-Type", "application/xml").
+func (r *Request) SetApplicationXML() *Request {
+	return r.SetHeader("Content-Type", applicationXML)
+}
+
 // SetMultipartFormData is an alias of req.SetHeader("Content-Type", "multipart/form-data").
 func (r *Request) SetMultipartFormData() *Request {
 	return r.SetHeader("Content-Type", multipartFormData)
 }
 
 // Send sends request.
-// If method is POST and content-type is application/x-www-form-urlencoded,
+// If content-type is application/x-www-form-urlencoded,
 // the request data is urlencoded.
-// If method is POST and content-type is application/json,
+// If content-type is application/json,
 // the request data is converted to json string.
+// Other content-types are encoded by the codec registered by RegisterCodec.
+// The request data is encoded whatever the method is.
+// If the request data is an io.Reader, it is streamed as it is.
 func (r *Request) Send() (res *Response, err error) {
 	s, err := NewSession()
 	if err != nil {
 		return
 	}
-	s.Timeout = r.Timeout
 	return send(s, r)
 }
 
@@ -244,9 +411,22 @@ func NewRequest(method, url string, body io.Reader, timeoutSecond int) (req *Req
 		Timeout: timeout,
 		Gzip:    false,
 	}
+	if body != nil {
+		err = req.SetBody(body)
+	}
 	return
 }
 
+// SetBody sets a request body which is streamed when the request is sent.
+// If body is an io.Seeker like *os.File, its length is sent as Content-Length
+// and it is rewound on redirects and retries.
+// (The caller must close it after the request is sent.)
+// Other readers are sent with chunked encoding only once.
+func (r *Request) SetBody(body io.Reader) error {
+	r.source = readerSource(body)
+	return r.setSource(r.source)
+}
+
 // DumpHeader returns a header string.
 func (r *Request) DumpHeader() (dump string, err error) {
 	b, err := httputil.DumpRequest(r.Request, false)
@@ -254,31 +434,44 @@ func (r *Request) DumpHeader() (dump string, err error) {
 }
 
 // Get make a request whose method is GET.
-func Get(url string) (req *Request, err error) {
-	req, err = NewRequest("GET", url, nil, DefaultTimeout)
+// If vars are given, url is expanded as a URI template by ExpandURL.
+func Get(url string, vars ...Vars) (req *Request, err error) {
+	req, err = newRequest("GET", url, vars)
 	return
 }
 
 // Delete make a request whose method is DELETE.
-func Delete(url string) (req *Request, err error) {
-	req, err = NewRequest("DELETE", url, nil, DefaultTimeout)
+// If vars are given, url is expanded as a URI template by ExpandURL.
+func Delete(url string, vars ...Vars) (req *Request, err error) {
+	req, err = newRequest("DELETE", url, vars)
 	return
 }
 
 // Head make a request whose method is HEAD.
-func Head(url string) (req *Request, err error) {
-	req, err = NewRequest("HEAD", url, nil, DefaultTimeout)
+// If vars are given, url is expanded as a URI template by ExpandURL.
+func Head(url string, vars ...Vars) (req *Request, err error) {
+	req, err = newRequest("HEAD", url, vars)
 	return
 }
 
 // Options make a request whose method is OPTIONS.
-func Options(url string) (req *Request, err error) {
-	req, err = NewRequest("OPTIONS", url, nil, DefaultTimeout)
+// If vars are given, url is expanded as a URI template by ExpandURL.
+func Options(url string, vars ...Vars) (req *Request, err error) {
+	req, err = newRequest("OPTIONS", url, vars)
 	return
 }
 
-func postOrPut(method, url string, data interface{}) (req *Request, err error) {
+func newRequest(method, url string, vars []Vars) (req *Request, err error) {
+	url, err = expandURL(url, vars)
+	if err != nil {
+		return
+	}
 	req, err = NewRequest(method, url, nil, DefaultTimeout)
+	return
+}
+
+func requestWithData(method, url string, data interface{}, vars []Vars) (req *Request, err error) {
+	req, err = newRequest(method, url, vars)
 	if err != nil {
 		return
 	}
@@ -289,13 +482,34 @@ func postOrPut(method, url string, data interface{}) (req *Request, err error) {
 }
 
 // Post make a request whose method is POST.
-func Post(url string, data interface{}) (req *Request, err error) {
-	req, err = postOrPut("POST", url, data)
+// If vars are given, url is expanded as a URI template by ExpandURL.
+func Post(url string, data interface{}, vars ...Vars) (req *Request, err error) {
+	req, err = requestWithData("POST", url, data, vars)
 	return
 }
 
 // Put make a request whose method is PUT.
-func Put(url string, data interface{}) (req *Request, err error) {
-	req, err = postOrPut("PUT", url, data)
+// If vars are given, url is expanded as a URI template by ExpandURL.
+func Put(url string, data interface{}, vars ...Vars) (req *Request, err error) {
+	req, err = requestWithData("PUT", url, data, vars)
+	return
+}
+
+// Patch make a request whose method is PATCH.
+// If vars are given, url is expanded as a URI template by ExpandURL.
+func Patch(url string, data interface{}, vars ...Vars) (req *Request, err error) {
+	req, err = requestWithData("PATCH", url, data, vars)
+	return
+}
+
+// Do make a request whose method is method.
+// The data is encoded like Post whatever the method is.
+// If vars are given, url is expanded as a URI template by ExpandURL.
+func Do(method, url string, data interface{}, vars ...Vars) (req *Request, err error) {
+	if data == nil {
+		req, err = newRequest(method, url, vars)
+		return
+	}
+	req, err = requestWithData(method, url, data, vars)
 	return
 }
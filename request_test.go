@@ -4,10 +4,12 @@ import (
 	"compress/gzip"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -205,12 +207,12 @@ func TestApplicationJSON(t *testing.T) {
 		defer r.Body.Close()
 		body, err := ioutil.ReadAll(r.Body)
 		if err != nil {
-			t.Fatalf(err.Error())
+			t.Fatal(err)
 		}
 		var list []string
 		err = json.Unmarshal(body, &list)
 		if err != nil {
-			t.Fatalf(err.Error())
+			t.Fatal(err)
 		}
 		if list[0] != "foo" || list[1] != "bar" {
 			t.Fatalf("Request data is wrong")
@@ -322,6 +324,79 @@ func TestPut(t *testing.T) {
 	}
 }
 
+func TestPatch(t *testing.T) {
+	req, _ := Patch("http://example.com", nil)
+	if req.Method != "PATCH" {
+		t.Fatalf("req.Method is wrong by Patch(). req.Method is %#v", req.Method)
+	}
+	if req.Timeout != time.Duration(DefaultTimeout)*time.Second {
+		t.Fatalf("req.Timeout is wrong by Patch(). req.Timeout is %#v", req.Timeout)
+	}
+	ct := req.HeaderValue("Content-Type")
+	if ct != DefaultContentType {
+		t.Fatalf("Content-Type is wrong by Patch(). Content-Type is %#v", ct)
+	}
+}
+
+func TestDo(t *testing.T) {
+	req, _ := Do("PURGE", "http://example.com", nil)
+	if req.Method != "PURGE" {
+		t.Fatalf("req.Method is wrong by Do(). req.Method is %#v", req.Method)
+	}
+	if req.HeaderValue("Content-Type") != "" {
+		t.Fatalf("Content-Type is wrong by Do().")
+	}
+	req, _ = Do("DELETE", "http://example.com", map[string]string{})
+	if req.HeaderValue("Content-Type") != DefaultContentType {
+		t.Fatalf("Content-Type is wrong by Do().")
+	}
+}
+
+func TestBodyOfEveryMethod(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType := r.Header.Get("Content-Type")
+		var foo string
+		switch {
+		case contentType == applicationFormUrlencoded:
+			b, _ := ioutil.ReadAll(r.Body)
+			foo = string(b)
+		case contentType == applicationJSON:
+			var data map[string]string
+			json.NewDecoder(r.Body).Decode(&data)
+			foo = "foo=" + data["foo"]
+		case strings.HasPrefix(contentType, multipartFormData):
+			reader, _ := r.MultipartReader()
+			part, _ := reader.NextPart()
+			b, _ := ioutil.ReadAll(part)
+			foo = part.FormName() + "=" + string(b)
+		}
+		fmt.Fprintf(w, "%s %s", r.Method, foo)
+	}))
+	defer server.Close()
+	data := map[string]string{"foo": "123"}
+	for _, method := range []string{"POST", "PUT", "PATCH", "DELETE", "OPTIONS"} {
+		for _, contentType := range []string{applicationFormUrlencoded, applicationJSON, multipartFormData} {
+			req, _ := Do(method, server.URL, data)
+			req.SetHeader("Content-Type", contentType)
+			res, err := req.Send()
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, _ := res.Text()
+			if text != method+" foo=123" {
+				t.Fatalf("body is wrong. %s %s %#v", method, contentType, text)
+			}
+		}
+	}
+	req, _ := Delete(server.URL)
+	req.Data = data
+	res, _ := req.Send()
+	text, _ := res.Text()
+	if text != "DELETE foo=123" {
+		t.Fatalf("body is wrong. %#v", text)
+	}
+}
+
 func TestSetTimeout(t *testing.T) {
 	req, _ := Get("http://example.com")
 	req.SetTimeout(100)
@@ -330,3 +405,163 @@ func TestSetTimeout(t *testing.T) {
 		t.Fatalf("req.SetTimeout() is wrong.")
 	}
 }
+
+func TestStreamBody(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			body, _ = gzip.NewReader(r.Body)
+		}
+		b, _ := ioutil.ReadAll(body)
+		w.Header().Set("X-Length", fmt.Sprint(r.ContentLength))
+		w.Header().Set("X-Chunked", fmt.Sprint(len(r.TransferEncoding) > 0))
+		if r.URL.Path == "/retry" {
+			count++
+		}
+		if r.URL.Path == "/retry" && count == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, "%s", b)
+	}))
+	defer server.Close()
+
+	// A reader which can not be rewound is sent with chunked encoding.
+	pr, pw := io.Pipe()
+	go func() {
+		fmt.Fprint(pw, "foo")
+		fmt.Fprint(pw, "bar")
+		pw.Close()
+	}()
+	req, _ := NewRequest("PUT", server.URL, pr, DefaultTimeout)
+	res, err := req.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := res.Text()
+	if text != "foobar" || res.HeaderValue("X-Chunked") != "true" {
+		t.Fatalf("stream body is wrong. text is %#v", text)
+	}
+
+	// A file is rewound on retries and sent with Content-Length.
+	file, _ := os.Open("test/foo.txt")
+	defer file.Close()
+	req, _ = NewRequest("PUT", server.URL+"/retry", file, DefaultTimeout)
+	req.SetRetry(&RetryPolicy{MaxAttempts: 2, StatusCodes: DefaultRetryStatusCodes})
+	res, err = req.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ = res.Text()
+	if text != "foobar\n" || res.Attempts != 2 || res.HeaderValue("X-Length") != "7" {
+		t.Fatalf("file body is wrong. text is %#v", text)
+	}
+
+	// Data of io.Reader is compressed on the fly.
+	req, _ = Post(server.URL, strings.NewReader("foo=123"))
+	res, err = req.UseGzip().Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ = res.Text()
+	if text != "foo=123" || res.HeaderValue("X-Chunked") != "true" {
+		t.Fatalf("gzip body is wrong. text is %#v", text)
+	}
+}
+
+func TestStreamMultipart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		if r.ContentLength >= 0 && int64(len(b)) != r.ContentLength {
+			t.Fatalf("Content-Length is wrong. %d != %d", len(b), r.ContentLength)
+		}
+		w.Header().Set("X-Length", fmt.Sprint(r.ContentLength))
+		r.Body = ioutil.NopCloser(strings.NewReader(string(b)))
+		r.ParseMultipartForm(32 << 20)
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(file)
+		fmt.Fprintf(w, "%s:%s:%s", r.FormValue("foo"), header.Filename, content)
+	}))
+	defer server.Close()
+
+	// A file is read again on a redirect.
+	file, _ := os.Open("test/foo.txt")
+	req, _ := Post(server.URL+"/redirect", map[string]string{"foo": "123"})
+	req.AddFile("text/plain", "file", `fo"o.txt`, file).SetMultipartFormData()
+	res, err := req.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := res.Text()
+	if text != "123:fo\"o.txt:foobar\n" {
+		t.Fatalf("text is wrong. text is %#v", text)
+	}
+	if res.HeaderValue("X-Length") == "-1" {
+		t.Fatalf("Content-Length is unknown.")
+	}
+
+	// A file whose size is unknown is sent with chunked encoding.
+	pr, pw, _ := os.Pipe()
+	go func() {
+		fmt.Fprint(pw, "foobar")
+		pw.Close()
+	}()
+	req, _ = Post(server.URL, map[string]string{"foo": "123"})
+	req.AddFile("text/plain", "file", "foo.txt", pr).SetMultipartFormData()
+	res, err = req.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ = res.Text()
+	if text != "123:foo.txt:foobar" || res.HeaderValue("X-Length") != "-1" {
+		t.Fatalf("text is wrong. text is %#v", text)
+	}
+}
+
+func TestMultipartFileSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, _ := r.MultipartReader()
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				break
+			}
+			b, _ := ioutil.ReadAll(part)
+			fmt.Fprintf(w, "%s|%s|%s|%s|%s\n", part.FormName(), part.FileName(),
+				part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), b)
+		}
+	}))
+	defer server.Close()
+	pathFile, err := NewFilePath("c", "test/foo.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := Post(server.URL, map[string]string{})
+	req.SetMultipartFormData()
+	req.AttachFile(
+		NewFileReader("text/csv", "a", "a.csv", strings.NewReader("x,y")),
+		NewFileBytes("", "b", "b.png", []byte("\x89PNG\x0D\x0A\x1A\x0A")).SetHeader("Content-Transfer-Encoding", "binary"),
+		pathFile,
+	)
+	res, err := req.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := res.Content()
+	text := string(b)
+	want := "a|a.csv|text/csv||x,y\n" +
+		"b|b.png|image/png|binary|\x89PNG\r\n\x1a\n\n" +
+		"c|foo.txt|text/plain; charset=utf-8||foobar\n\n"
+	if text != want {
+		t.Fatalf("text is wrong. text is %#v", text)
+	}
+}
@@ -2,8 +2,8 @@ package hrq
 
 import (
 	"bytes"
-	"compress/gzip"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
@@ -18,7 +18,15 @@ type Response struct {
 	*http.Response
 	// History is the redirect history.
 	History []*http.Request
-	rawBody []byte
+	// Redirects are the redirect responses in the order of History.
+	// Their bodies are already closed.
+	Redirects []*http.Response
+	// Attempts is the number of attempts to get this response.
+	Attempts     int
+	rawBody      []byte
+	receivedBody []byte
+	limit        *BodyLimit
+	bodyErr      error
 }
 
 // URL returns a request url.
@@ -64,28 +72,64 @@ func (r *Response) HeaderValue(name string) string {
 }
 
 // Content returns response body by byte.
+// It is decompressed by Content-Encoding (gzip, deflate, br, zstd and the stack of them).
+// If the body exceeds BodyLimit, it returns *BodyTooLargeError.
 func (r *Response) Content() (bs []byte, err error) {
 	if r.rawBody != nil {
 		return r.rawBody, nil
 	}
-	defer r.Body.Close()
+	if r.bodyErr != nil {
+		return nil, r.bodyErr
+	}
+	received, err := r.RawContent()
+	if err != nil {
+		return nil, err
+	}
 	encoding := r.HeaderValue("Content-Encoding")
-	body := r.Body
-	if encoding == "gzip" {
-		body, err = gzip.NewReader(r.Body)
-		if err != nil {
-			return nil, err
-		}
-		defer body.Close()
+	if len(received) == 0 || encoding == "" {
+		r.rawBody = received
+		return received, nil
+	}
+	body, err := newDecodedReader(bytes.NewReader(received), encoding)
+	if err != nil {
+		return nil, err
 	}
-	bs, err = ioutil.ReadAll(body)
+	defer body.Close()
+	kind, limit := r.limit.decodedLimit(len(received))
+	bs, err = readLimited(body, kind, limit)
 	if err != nil {
+		r.bodyErr = err
 		return nil, err
 	}
 	r.rawBody = bs
 	return bs, err
 }
 
+// RawContent returns response body as it is received without decompression.
+func (r *Response) RawContent() (bs []byte, err error) {
+	if r.receivedBody != nil {
+		return r.receivedBody, nil
+	}
+	if r.bodyErr != nil {
+		return nil, r.bodyErr
+	}
+	defer r.Body.Close()
+	kind, limit := r.limit.receivedLimit(r.HeaderValue("Content-Encoding") != "")
+	bs, err = readLimited(r.Body, kind, limit)
+	if err != nil {
+		r.bodyErr = err
+		return nil, err
+	}
+	r.receivedBody = bs
+	return bs, err
+}
+
+// discard drains and closes the body so that the connection can be reused.
+func (r *Response) discard() {
+	io.CopyN(ioutil.Discard, r.Body, 4096)
+	r.Body.Close()
+}
+
 // ContentType returns content-type in response header..
 func (r *Response) ContentType() string {
 	return r.HeaderValue("Content-Type")
@@ -134,3 +178,16 @@ func (r *Response) JSON(t interface{}) error {
 	err = json.Unmarshal(rawBody, t)
 	return err
 }
+
+// Decode decodes response body by the codec for the response content-type.
+func (r *Response) Decode(v interface{}) error {
+	codec := LookupCodec(r.ContentType())
+	if codec == nil {
+		return noCodecError(r.ContentType())
+	}
+	rawBody, err := r.Content()
+	if err != nil {
+		return err
+	}
+	return codec.Unmarshal(rawBody, v)
+}
@@ -0,0 +1,203 @@
+package hrq
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// DefaultRetryStatusCodes are status codes retried by NewRetryPolicy.
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy decides whether and when a request is sent again.
+type RetryPolicy struct {
+	// MaxAttempts is the max number of attempts including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry.
+	BaseDelay time.Duration
+	// MaxDelay is the upper limit of the delay.
+	// When Retry-After is longer than it, the request is not retried.
+	// (0 means no limit)
+	MaxDelay time.Duration
+	// Multiplier is the growth factor of the delay.
+	// (default 2)
+	Multiplier float64
+	// Jitter is the ratio by which the delay is randomized.
+	// 0.2 makes the delay from 80% to 120%.
+	Jitter float64
+	// Backoff returns the delay before the next attempt.
+	// If it is set, BaseDelay, MaxDelay, Multiplier and Jitter are ignored.
+	Backoff func(attempt int) time.Duration
+	// StatusCodes are status codes which are retried.
+	StatusCodes []int
+	// RetryError decides whether an error is retried.
+	// (default: timeouts, refused or reset connections and unexpected EOF are retried)
+	RetryError func(err error) bool
+	// AllMethods allows retrying POST, PATCH and so on.
+	// (default false: only idempotent methods are retried)
+	AllMethods bool
+}
+
+// NewRetryPolicy returns a retry policy with exponential backoff.
+func NewRetryPolicy(maxAttempts int) *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: maxAttempts,
+		BaseDelay:   100 * time.Millisecond,
+		MaxDelay:    30 * time.Second,
+		Multiplier:  2,
+		Jitter:      0.2,
+		StatusCodes: DefaultRetryStatusCodes,
+	}
+}
+
+func (r *Request) retryPolicy(session *Session) *RetryPolicy {
+	if r.Retry != nil {
+		return r.Retry
+	}
+	return session.Retry
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// isRetryableError reports whether err is a transient error of the connection.
+// Errors of redirects, TLS and invalid urls are not retried.
+func isRetryableError(err error) bool {
+	// *url.Error is a net.Error, so the cause is checked.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	if errors.Is(err, ErrTooManyRedirects) || errors.Is(err, ErrRedirectNotAllowed) || isTLSError(err) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// next returns the delay before the next attempt and whether to retry.
+func (p *RetryPolicy) next(attempt int, r *Request, res *Response, err error) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if !p.AllMethods && !isIdempotent(r.Method) {
+		return 0, false
+	}
+	if r.Context().Err() != nil {
+		return 0, false
+	}
+	if err != nil {
+		retryError := p.RetryError
+		if retryError == nil {
+			retryError = isRetryableError
+		}
+		return p.delay(attempt), retryError(err)
+	}
+	if !p.retryableStatus(res.StatusCode) {
+		return 0, false
+	}
+	delay := p.delay(attempt)
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode == http.StatusServiceUnavailable {
+		if after, ok := retryAfter(res.HeaderValue("Retry-After")); ok {
+			if p.MaxDelay > 0 && after > p.MaxDelay {
+				return 0, false
+			}
+			delay = after
+		}
+	}
+	return delay, true
+}
+
+func (p *RetryPolicy) retryableStatus(code int) bool {
+	for _, c := range p.StatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *RetryPolicy) delay(attempt int) time.Duration {
+	if p.Backoff != nil {
+		return p.Backoff(attempt)
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	if p.BaseDelay <= 0 {
+		return 0
+	}
+	delay := float64(p.BaseDelay) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxDelay > 0 && delay > float64(p.MaxDelay) {
+		delay = float64(p.MaxDelay)
+	}
+	// The delay is limited to the max of time.Duration not to overflow.
+	delay = math.Min(delay, math.MaxInt64)
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	if delay >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+// wait sleeps for the delay unless the context is done.
+func wait(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryAfter parses Retry-After header which is seconds or http date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	t, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	after := time.Until(t)
+	if after < 0 {
+		after = 0
+	}
+	return after, true
+}
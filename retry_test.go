@@ -0,0 +1,276 @@
+package hrq
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryStatus(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		if count < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprintf(w, "foobar")
+	}))
+	defer server.Close()
+	req, _ := Get(server.URL)
+	req.SetRetry(&RetryPolicy{MaxAttempts: 5, StatusCodes: DefaultRetryStatusCodes})
+	res, err := req.Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := res.Text()
+	if text != "foobar" {
+		t.Fatalf("text is wrong. text is %#v", text)
+	}
+	if res.Attempts != 3 {
+		t.Fatalf("Attempts is wrong. Attempts is %#v", res.Attempts)
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	session, _ := NewSession()
+	session.Retry = &RetryPolicy{MaxAttempts: 2, StatusCodes: DefaultRetryStatusCodes}
+	req, _ := Get(server.URL)
+	res, _ := session.Send(req)
+	if res.StatusCode != http.StatusServiceUnavailable || res.Attempts != 2 || count != 2 {
+		t.Fatalf("Retry is wrong. status is %d, attempts is %d", res.StatusCode, res.Attempts)
+	}
+}
+
+func TestRetryIdempotent(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	req, _ := Post(server.URL, map[string]string{"foo": "123"})
+	req.SetRetry(NewRetryPolicy(3))
+	res, _ := req.Send()
+	if res.Attempts != 1 || count != 1 {
+		t.Fatalf("POST must not be retried. attempts is %d", res.Attempts)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var first time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if first.IsZero() {
+			first = time.Now()
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if time.Since(first) < time.Second {
+			t.Errorf("Retry-After is ignored.")
+		}
+	}))
+	defer server.Close()
+	req, _ := Get(server.URL)
+	req.SetRetry(NewRetryPolicy(2))
+	res, _ := req.Send()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status is wrong. status is %d", res.StatusCode)
+	}
+	d, ok := retryAfter(time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if !ok || d <= 59*time.Minute {
+		t.Fatalf("retryAfter() is wrong. d is %v", d)
+	}
+	req, _ = Get(server.URL)
+	first = time.Time{}
+	req.SetRetry(&RetryPolicy{MaxAttempts: 2, MaxDelay: time.Millisecond, StatusCodes: DefaultRetryStatusCodes})
+	res, _ = req.Send()
+	if res.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Retry-After longer than MaxDelay must not be retried.")
+	}
+}
+
+func TestRetryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+	req, _ := Get(url)
+	req.SetRetry(&RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	res, err := req.Send()
+	if err == nil || res != nil {
+		t.Fatalf("err is wrong. err is %#v", err)
+	}
+}
+
+func TestRetryBody(t *testing.T) {
+	type body struct {
+		contentType string
+		read        func(r *http.Request) string
+	}
+	bodies := []body{
+		{applicationFormUrlencoded, func(r *http.Request) string {
+			r.ParseForm()
+			return r.PostForm.Get("foo")
+		}},
+		{applicationJSON, func(r *http.Request) string {
+			var data map[string]string
+			json.NewDecoder(r.Body).Decode(&data)
+			return data["foo"]
+		}},
+		{multipartFormData, func(r *http.Request) string {
+			r.ParseMultipartForm(32 << 20)
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				return ""
+			}
+			b, _ := ioutil.ReadAll(file)
+			return r.FormValue("foo") + string(b)
+		}},
+	}
+	for _, b := range bodies {
+		for _, useGzip := range []bool{false, true} {
+			count := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				count++
+				if useGzip {
+					reader, err := gzip.NewReader(r.Body)
+					if err != nil {
+						t.Errorf("body is not gzip. %s", b.contentType)
+						return
+					}
+					r.Body = reader
+				}
+				v := b.read(r)
+				want := "123"
+				if b.contentType == multipartFormData {
+					want = "123foobar\n"
+				}
+				if v != want {
+					t.Errorf("body is wrong at attempt %d. %s %#v", count, b.contentType, v)
+				}
+				if count < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			req, _ := Post(server.URL, map[string]string{"foo": "123"})
+			req.SetHeader("Content-Type", b.contentType)
+			if b.contentType == multipartFormData {
+				file, _ := os.Open("test/foo.txt")
+				req.AddFile("text/plain", "file", "foo.txt", file)
+			}
+			if useGzip {
+				req.UseGzip()
+			}
+			policy := NewRetryPolicy(3)
+			policy.AllMethods = true
+			policy.BaseDelay = time.Millisecond
+			res, err := req.SetRetry(policy).Send()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != http.StatusOK || res.Attempts != 3 {
+				t.Fatalf("Retry is wrong. %s status is %d", b.contentType, res.StatusCode)
+			}
+			server.Close()
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	policy := &RetryPolicy{BaseDelay: time.Second, Jitter: 0.5}
+	for _, attempt := range []int{1, 10, 100, 10000} {
+		if delay := policy.delay(attempt); delay <= 0 {
+			t.Fatalf("delay must not overflow. %d %v", attempt, delay)
+		}
+	}
+	policy = &RetryPolicy{BaseDelay: time.Second}
+	if delay := policy.delay(10000); delay != math.MaxInt64 {
+		t.Fatalf("delay must be limited. %v", delay)
+	}
+	policy = &RetryPolicy{BaseDelay: time.Second, MaxDelay: time.Minute}
+	if delay := policy.delay(10000); delay != time.Minute {
+		t.Fatalf("delay must be limited by MaxDelay. %v", delay)
+	}
+}
+
+func TestRetryErrorKinds(t *testing.T) {
+	hits := map[string]int{}
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		count := hits[r.URL.Path]
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		case "/away":
+			http.Redirect(w, r, "http://example.com/", http.StatusFound)
+		case "/reset":
+			if count == 1 {
+				// A part of the response is sent so that http.Transport does not retry it.
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				io.WriteString(conn, "HTTP/1.1 200")
+				conn.Close()
+				return
+			}
+			io.WriteString(w, "ok")
+		}
+	}))
+	defer server.Close()
+	var handshakes int32
+	tlsServer := httptest.NewUnstartedServer(http.NotFoundHandler())
+	tlsServer.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&handshakes, 1)
+		}
+	}
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+	session, _ := NewSession()
+	session.Retry = &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	send := func(url string, policy *RedirectPolicy) (*Response, error) {
+		req, _ := Get(url)
+		return session.Send(req.SetRedirect(policy))
+	}
+	if _, err := send(server.URL+"/loop", nil); !errors.Is(err, ErrTooManyRedirects) || hits["/loop"] != DefaultMaxRedirects+1 {
+		t.Fatalf("too many redirects must not be retried. %d %v", hits["/loop"], err)
+	}
+	if _, err := send(server.URL+"/away", &RedirectPolicy{SameHost: true}); !errors.Is(err, ErrRedirectNotAllowed) || hits["/away"] != 1 {
+		t.Fatalf("a redirect which is not allowed must not be retried. %d %v", hits["/away"], err)
+	}
+	if _, err := send(tlsServer.URL, nil); !errors.Is(err, ErrTLS) || atomic.LoadInt32(&handshakes) != 1 {
+		t.Fatalf("TLS errors must not be retried. %d %v", handshakes, err)
+	}
+	if _, err := send("ftp://"+server.Listener.Addr().String(), nil); err == nil || isRetryableError(err) {
+		t.Fatalf("unsupported scheme must not be retried. %v", err)
+	}
+	res, err := send(server.URL+"/reset", nil)
+	if err != nil || res.Attempts != 2 || hits["/reset"] != 2 {
+		t.Fatalf("a closed connection must be retried. %d %v", hits["/reset"], err)
+	}
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	addr := listener.Addr().String()
+	listener.Close()
+	if _, err = send("http://"+addr, nil); !isRetryableError(err) {
+		t.Fatalf("connection refused must be retried. %v", err)
+	}
+}
@@ -5,11 +5,48 @@ import (
 	"net/http/cookiejar"
 	Url "net/url"
 	"strings"
+	"time"
 )
 
+// Handler sends a request and returns the response.
+type Handler func(r *Request) (*Response, error)
+
+// Middleware wraps a Handler.
+// It can change the request before calling next,
+// change the response after calling next,
+// or return a response without calling next.
+type Middleware func(next Handler) Handler
+
 // Session is a session.
 type Session struct {
 	*http.Client
+	// Retry is the retry policy of requests sent by this session.
+	Retry *RetryPolicy
+	// BodyLimit limits the size of response bodies.
+	BodyLimit *BodyLimit
+	// RaiseForStatus makes Send return *HTTPError if the status is 4xx or 5xx.
+	RaiseForStatus bool
+	// Redirect is the redirect policy of requests sent by this session.
+	// Session.CheckRedirect is called after it allows a redirect.
+	Redirect *RedirectPolicy
+	// MetaRefresh makes the session follow <meta http-equiv="refresh"> of HTML responses.
+	// They are recorded in Response.History and counted against the redirect limit.
+	MetaRefresh bool
+	// MaxMetaRefreshDelay is the max delay of meta refresh to follow.
+	// A refresh with a longer delay is returned as it is.
+	// (0 means DefaultMaxMetaRefreshDelay)
+	MaxMetaRefreshDelay time.Duration
+	// BaseURL is the base of relative request urls.
+	// "/users/1" is resolved to "http://example.com/v1/users/1"
+	// when BaseURL is "http://example.com/v1".
+	BaseURL *Url.URL
+	// Header is the default request header.
+	Header http.Header
+	// Params is the default query parameters.
+	Params Url.Values
+	// Cookies is the default cookies.
+	Cookies     []*http.Cookie
+	middlewares []Middleware
 }
 
 // NewSession return a session.
@@ -23,14 +60,37 @@ func NewSession() (s *Session, err error) {
 	}
 	s = &Session{
 		Client: cli,
+		Header: http.Header{},
+		Params: Url.Values{},
 	}
 	return
 }
 
 // Send send a request.
+// A session is safe for concurrent use by multiple goroutines.
+// Request.Timeout is applied to each request by its context,
+// so the http.Client of the session is never modified.
 func (s *Session) Send(r *Request) (res *Response, err error) {
-	s.Timeout = r.Timeout
-	return send(s, r)
+	s.setDefaults(r)
+	handler := func(r *Request) (*Response, error) {
+		return send(s, r)
+	}
+	for i := len(s.middlewares) - 1; i >= 0; i-- {
+		handler = s.middlewares[i](handler)
+	}
+	res, err = handler(r)
+	if err == nil && s.MetaRefresh {
+		return s.followMetaRefresh(handler, r, res)
+	}
+	return
+}
+
+// Use adds middlewares to the session.
+// They are called in the order of registration before a request is sent.
+// Use must not be called while the session sends requests.
+func (s *Session) Use(middlewares ...Middleware) *Session {
+	s.middlewares = append(s.middlewares, middlewares...)
+	return s
 }
 
 // CookieValue returns a cookie value.
@@ -45,3 +105,93 @@ func (s *Session) CookieValue(url, name string) string {
 	}
 	return ""
 }
+
+// SetBaseURL sets the base of relative request urls.
+func (s *Session) SetBaseURL(baseURL string) error {
+	u, err := Url.Parse(baseURL)
+	if err != nil {
+		return err
+	}
+	s.BaseURL = u
+	return nil
+}
+
+// SetHeader sets a value of the default request header.
+func (s *Session) SetHeader(key, value string) *Session {
+	if s.Header == nil {
+		s.Header = http.Header{}
+	}
+	s.Header.Set(key, value)
+	return s
+}
+
+// SetParam sets a value of the default query parameters.
+func (s *Session) SetParam(key, value string) *Session {
+	if s.Params == nil {
+		s.Params = Url.Values{}
+	}
+	s.Params.Set(key, value)
+	return s
+}
+
+// PutCookie makes a cookie which is setted name and value.
+// It adds a cookie to the default cookies.
+func (s *Session) PutCookie(name, value string) *Session {
+	s.Cookies = append(s.Cookies, &http.Cookie{Name: name, Value: value})
+	return s
+}
+
+// setDefaults applies the session defaults which the request does not override.
+func (s *Session) setDefaults(r *Request) {
+	r.URL = s.resolveURL(r.URL)
+	if r.Host == "" {
+		r.Host = r.URL.Host
+	}
+	for k, v := range s.Header {
+		if _, ok := r.Header[k]; !ok {
+			r.Header[k] = append([]string{}, v...)
+		}
+	}
+	query := r.URL.Query()
+	params := Url.Values{}
+	for k, v := range s.Params {
+		if _, ok := query[k]; !ok {
+			params[k] = v
+		}
+	}
+	if len(params) > 0 {
+		if r.URL.RawQuery != "" {
+			r.URL.RawQuery += "&"
+		}
+		r.URL.RawQuery += params.Encode()
+	}
+	for _, c := range s.Cookies {
+		if _, err := r.Cookie(c.Name); err == http.ErrNoCookie {
+			r.AddCookie(c)
+		}
+	}
+}
+
+// resolveURL joins a relative url to BaseURL.
+func (s *Session) resolveURL(u *Url.URL) *Url.URL {
+	if s.BaseURL == nil || u.IsAbs() || u.Host != "" {
+		return u
+	}
+	resolved := *s.BaseURL
+	if u.Path != "" {
+		joined := strings.TrimSuffix(resolved.EscapedPath(), "/") + "/" + strings.TrimPrefix(u.EscapedPath(), "/")
+		path, err := Url.PathUnescape(joined)
+		if err == nil {
+			resolved.Path = path
+			resolved.RawPath = joined
+		}
+	}
+	if u.RawQuery != "" {
+		if resolved.RawQuery != "" {
+			resolved.RawQuery += "&"
+		}
+		resolved.RawQuery += u.RawQuery
+	}
+	resolved.Fragment = u.Fragment
+	return &resolved
+}
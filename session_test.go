@@ -1,10 +1,14 @@
 package hrq
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSession(t *testing.T) {
@@ -75,3 +79,164 @@ func TestSession(t *testing.T) {
 		t.Fatalf("session.CookieValue() is wrong.")
 	}
 }
+
+func TestSessionConcurrency(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Query().Get("n"))
+		if n > 0 {
+			u := fmt.Sprintf("/?n=%d&sleep=%s", n-1, r.URL.Query().Get("sleep"))
+			http.Redirect(w, r, u, http.StatusFound)
+			return
+		}
+		if r.URL.Query().Get("sleep") == "1" {
+			time.Sleep(500 * time.Millisecond)
+		}
+		fmt.Fprintf(w, "foobar")
+	}))
+	defer server.Close()
+	session, _ := NewSession()
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			redirects := i % 5
+			slow := i%2 == 0
+			params := map[string]string{"n": strconv.Itoa(redirects), "sleep": "0"}
+			if slow {
+				params["sleep"] = "1"
+			}
+			req, _ := Get(MakeURL(server.URL, params))
+			if slow {
+				req.Timeout = 100 * time.Millisecond
+			}
+			res, err := session.Send(req)
+			if slow {
+				if err == nil {
+					t.Errorf("timeout is wrong. i is %d", i)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("err is wrong. err is %#v", err)
+				return
+			}
+			text, _ := res.Text()
+			if text != "foobar" {
+				t.Errorf("text is wrong. text is %#v", text)
+			}
+			if len(res.History) != redirects {
+				t.Errorf("History is wrong. want %d, got %d", redirects, len(res.History))
+			}
+		}(i)
+	}
+	wg.Wait()
+	if session.CheckRedirect != nil || session.Timeout != 0 {
+		t.Fatalf("session.Client is modified.")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	count := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count++
+		r.ParseForm()
+		if r.PostForm.Get("foo") != "456" {
+			t.Fatalf("Request.Data is not changed by middleware. %#v", r.PostForm)
+		}
+		fmt.Fprintf(w, "%s", r.Header.Get("X-Order"))
+	}))
+	defer server.Close()
+	order := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(r *Request) (*Response, error) {
+				r.SetHeader("X-Order", r.HeaderValue("X-Order")+name)
+				res, err := next(r)
+				if err == nil {
+					res.Header.Add("X-After", name)
+				}
+				return res, err
+			}
+		}
+	}
+	cache := map[string]*Response{}
+	cached := func(next Handler) Handler {
+		return func(r *Request) (*Response, error) {
+			if res, ok := cache[r.URL.String()]; ok {
+				return res, nil
+			}
+			res, err := next(r)
+			if err == nil {
+				res.Content()
+				cache[r.URL.String()] = res
+			}
+			return res, err
+		}
+	}
+	data := func(next Handler) Handler {
+		return func(r *Request) (*Response, error) {
+			r.Data = map[string]string{"foo": "456"}
+			return next(r)
+		}
+	}
+	session, _ := NewSession()
+	session.Use(cached, order("a"), order("b")).Use(data)
+	for i := 0; i < 2; i++ {
+		req, _ := Post(server.URL, map[string]string{"foo": "123"})
+		res, err := session.Send(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, _ := res.Text()
+		if text != "ab" {
+			t.Fatalf("text is wrong. text is %#v", text)
+		}
+		after := res.Header["X-After"]
+		if len(after) != 2 || after[0] != "b" || after[1] != "a" {
+			t.Fatalf("X-After is wrong. %#v", after)
+		}
+	}
+	if count != 1 {
+		t.Fatalf("middleware can not short-circuit. count is %d", count)
+	}
+}
+
+func TestSessionDefaults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/users/1" {
+			t.Fatalf("path is wrong. path is %#v", r.URL.Path)
+		}
+		query := r.URL.Query()
+		if query.Get("key") != "abc" || query.Get("lang") != "en" || query.Get("q") != "foo" {
+			t.Fatalf("query is wrong. query is %#v", query)
+		}
+		if r.Header.Get("X-Foo") != "bar" || r.Header.Get("User-Agent") != "hrq-test" {
+			t.Fatalf("header is wrong. header is %#v", r.Header)
+		}
+		c1, _ := r.Cookie("c1")
+		c2, _ := r.Cookie("c2")
+		if c1.Value != "v1" || c2.Value != "v3" || len(r.Cookies()) != 2 {
+			t.Fatalf("cookie is wrong. cookies are %#v", r.Cookies())
+		}
+	}))
+	defer server.Close()
+	session, _ := NewSession()
+	session.SetBaseURL(server.URL + "/v1/")
+	session.SetHeader("X-Foo", "bar").SetHeader("User-Agent", "hrq")
+	session.SetParam("key", "abc").SetParam("lang", "ja")
+	session.PutCookie("c1", "v1").PutCookie("c2", "v2")
+	req, _ := Get("/users/1?lang=en&q=foo")
+	req.SetHeader("User-Agent", "hrq-test")
+	req.PutCookie("c2", "v3")
+	res, err := session.Send(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status is wrong. status is %d", res.StatusCode)
+	}
+	req, _ = Get("http://example.com/users/1")
+	if u := session.resolveURL(req.URL); u.String() != "http://example.com/users/1" {
+		t.Fatalf("absolute url is wrong. url is %#v", u.String())
+	}
+}
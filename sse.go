@@ -0,0 +1,199 @@
+package hrq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const textEventStream = "text/event-stream"
+
+// DefaultEventRetry is the delay before reconnecting to an event stream
+// until the server sends a retry field.
+var DefaultEventRetry = 3 * time.Second
+
+// Event is an event of Server-Sent Events.
+type Event struct {
+	// ID is the last event ID.
+	ID string
+	// Event is the event type. (default "message")
+	Event string
+	Data  string
+	// Retry is the reconnection time sent with this event. (0 if it is not sent)
+	Retry time.Duration
+}
+
+// Events receives Server-Sent Events of the request and calls fn for each event.
+// It reconnects with Last-Event-ID when the connection is closed or fails,
+// and waits for the retry interval sent by the server before reconnecting.
+// It stops when ctx is done, fn returns an error,
+// the server responds 204 No Content, the response is not an event stream,
+// or the body fails by an error other than a closed connection
+// like a too long line or BodyLimit.
+// Request.Timeout is ignored because an event stream lasts long.
+func (s *Session) Events(ctx context.Context, r *Request, fn func(event *Event) error) error {
+	r.WithContext(ctx)
+	r.Timeout = 0
+	r.SetHeader("Accept", textEventStream)
+	r.SetHeader("Cache-Control", "no-cache")
+	stream := &eventStream{retry: DefaultEventRetry}
+	for {
+		if stream.lastID != "" {
+			r.SetHeader("Last-Event-ID", stream.lastID)
+		} else {
+			r.DelHeader("Last-Event-ID")
+		}
+		res, err := s.Send(r)
+		var httpErr *HTTPError
+		if errors.As(err, &httpErr) {
+			return err
+		}
+		if err == nil {
+			if res.StatusCode == http.StatusNoContent {
+				res.discard()
+				return nil
+			}
+			if res.StatusCode != http.StatusOK || mediaType(res.ContentType()) != textEventStream {
+				res.discard()
+				return fmt.Errorf("hrq: the response is not an event stream (status %d, content-type %q)", res.StatusCode, res.ContentType())
+			}
+			if err = stream.read(res, fn); err != nil {
+				return err
+			}
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err = wait(ctx, stream.retry); err != nil {
+			return err
+		}
+	}
+}
+
+// Subscribe receives Server-Sent Events of the request in a goroutine.
+// The events channel is closed when Events stops,
+// and then the error channel receives the error which stopped it.
+func (s *Session) Subscribe(ctx context.Context, r *Request) (<-chan *Event, <-chan error) {
+	events := make(chan *Event)
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		err := s.Events(ctx, r, func(event *Event) error {
+			select {
+			case events <- event:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(events)
+		errc <- err
+	}()
+	return events, errc
+}
+
+// eventStream keeps the state of an event stream over connections.
+type eventStream struct {
+	lastID string
+	retry  time.Duration
+}
+
+// read parses an event stream until the body ends.
+// It returns the error of fn or the body.
+// A transient error of the connection is not returned, so that it reconnects.
+func (s *eventStream) read(res *Response, fn func(event *Event) error) error {
+	body, err := res.Reader()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 4096), 1<<20)
+	scanner.Split(scanEventLines)
+	var eventType string
+	var data strings.Builder
+	var retry time.Duration
+	first := true
+	for scanner.Scan() {
+		line := scanner.Text()
+		if first {
+			line = strings.TrimPrefix(line, "\ufeff")
+			first = false
+		}
+		if line == "" {
+			if data.Len() > 0 {
+				event := &Event{
+					ID:    s.lastID,
+					Event: eventType,
+					Data:  strings.TrimSuffix(data.String(), "\n"),
+					Retry: retry,
+				}
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				if err = fn(event); err != nil {
+					return err
+				}
+			}
+			eventType, retry = "", 0
+			data.Reset()
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			eventType = value
+		case "data":
+			data.WriteString(value)
+			data.WriteString("\n")
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				s.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 63); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+				s.retry = retry
+			}
+		}
+	}
+	if err = scanner.Err(); err != nil && !isRetryableError(err) {
+		return err
+	}
+	return nil
+}
+
+// scanEventLines splits an event stream into lines ended by CRLF, LF or CR.
+func scanEventLines(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		if i+1 < len(data) {
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		}
+		if atEOF {
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
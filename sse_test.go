@@ -0,0 +1,135 @@
+package hrq
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvents(t *testing.T) {
+	connections := 0
+	lastIDs := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		lastIDs = append(lastIDs, r.Header.Get("Last-Event-ID"))
+		switch connections {
+		case 1:
+			w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+			fmt.Fprint(w, "\ufeff: comment\nretry: 10\nid: 1\ndata: foo\n\n")
+		case 2:
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "event: update\r\ndata: bar\r\ndata:baz\r\n\r\nid: 2\rdata\r\rdata: ignored")
+		case 3:
+			// An empty id resets the last event ID.
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "id:\ndata: reset\n\n")
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+	session, _ := NewSession()
+	req, _ := Get(server.URL)
+	events := []*Event{}
+	err := session.Events(context.Background(), req, func(event *Event) error {
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Event{
+		{ID: "1", Event: "message", Data: "foo", Retry: 10 * time.Millisecond},
+		{ID: "1", Event: "update", Data: "bar\nbaz"},
+		{ID: "2", Event: "message", Data: ""},
+		{ID: "", Event: "message", Data: "reset"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("events are wrong. %#v", events)
+	}
+	for i, event := range events {
+		if *event != want[i] {
+			t.Fatalf("event is wrong. %#v", event)
+		}
+	}
+	if fmt.Sprint(lastIDs) != "[ 1 2 ]" {
+		t.Fatalf("Last-Event-ID is wrong. %#v", lastIDs)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept") != "text/event-stream" {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: foo\n\n")
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	session, _ := NewSession()
+	req, _ := Get(server.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	events, errc := session.Subscribe(ctx, req)
+	event := <-events
+	if event.Data != "foo" {
+		t.Fatalf("event is wrong. %#v", event)
+	}
+	cancel()
+	for range events {
+	}
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("error is wrong. %#v", err)
+	}
+	// A response which is not an event stream stops Events.
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	req, _ = Get(server.URL)
+	err := session.Events(context.Background(), req, func(event *Event) error {
+		return nil
+	})
+	if err == nil {
+		t.Fatalf("Events() must fail for status 500.")
+	}
+}
+
+func TestEventsBodyError(t *testing.T) {
+	connections := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connections++
+		w.Header().Set("Content-Type", "text/event-stream")
+		if r.URL.Path == "/long" {
+			fmt.Fprintf(w, "data: %s\n\n", strings.Repeat("a", 1<<20))
+			return
+		}
+		fmt.Fprint(w, "data: foo\n\ndata: bar\n\n")
+	}))
+	defer server.Close()
+	session, _ := NewSession()
+	req, _ := Get(server.URL + "/long")
+	err := session.Events(context.Background(), req, func(event *Event) error {
+		return nil
+	})
+	if err != bufio.ErrTooLong || connections != 1 {
+		t.Fatalf("a too long line must stop Events. %d %v", connections, err)
+	}
+	connections = 0
+	req, _ = Get(server.URL)
+	events := 0
+	err = session.Events(context.Background(), req.SetBodyLimit(&BodyLimit{MaxSize: 15}), func(event *Event) error {
+		events++
+		return nil
+	})
+	if _, ok := err.(*BodyTooLargeError); !ok || connections != 1 || events != 1 {
+		t.Fatalf("BodyLimit must stop Events. %d %d %v", connections, events, err)
+	}
+}
@@ -0,0 +1,170 @@
+package hrq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+var errStreamed = errors.New("hrq: response body is already read as a stream")
+
+// readCloser closes a reader by close.
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r *readCloser) Close() error {
+	return r.close()
+}
+
+// Reader returns a reader of response body decompressed by Content-Encoding.
+// The body is not cached, so it can be read only once
+// unless Content or RawContent has been called before.
+// BodyLimit is applied while it is read.
+func (r *Response) Reader() (io.ReadCloser, error) {
+	if r.rawBody != nil {
+		return ioutil.NopCloser(bytes.NewReader(r.rawBody)), nil
+	}
+	if r.bodyErr != nil {
+		return nil, r.bodyErr
+	}
+	var body io.ReadCloser = r.Body
+	encoding := r.HeaderValue("Content-Encoding")
+	kind, limit := r.limit.receivedLimit(encoding != "")
+	if r.receivedBody != nil {
+		body = ioutil.NopCloser(bytes.NewReader(r.receivedBody))
+		limit = 0
+	} else {
+		r.bodyErr = errStreamed
+	}
+	received := &limitReader{reader: body, limit: func() (string, int64) {
+		return kind, limit
+	}}
+	if encoding == "" {
+		return &readCloser{Reader: received, close: body.Close}, nil
+	}
+	decoded, err := newDecodedReader(received, encoding)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &readCloser{
+		Reader: &limitReader{reader: decoded, limit: func() (string, int64) {
+			return r.limit.decodedLimit(int(received.read))
+		}},
+		close: func() error {
+			decoded.Close()
+			return body.Close()
+		},
+	}, nil
+}
+
+// TextReader returns a reader of response body converted to UTF-8.
+// The encoding is determined by content-type and the beginning of the body.
+func (r *Response) TextReader() (io.ReadCloser, error) {
+	body, err := r.Reader()
+	if err != nil {
+		return nil, err
+	}
+	text, err := charset.NewReader(body, r.ContentType())
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &readCloser{Reader: text, close: body.Close}, nil
+}
+
+// Lines calls fn for each line of response body converted to UTF-8.
+// The line does not contain "\n" and "\r\n".
+func (r *Response) Lines(fn func(line string) error) error {
+	text, err := r.TextReader()
+	if err != nil {
+		return err
+	}
+	defer text.Close()
+	reader := bufio.NewReader(text)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+			if e := fn(line); e != nil {
+				return e
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Chunks calls fn for each chunk of response body whose size is size.
+// The last chunk may be shorter.
+// The chunk is reused, so fn must copy it to keep it.
+// size must be positive.
+func (r *Response) Chunks(size int, fn func(chunk []byte) error) error {
+	if size <= 0 {
+		return fmt.Errorf("hrq: chunk size must be positive: %d", size)
+	}
+	body, err := r.Reader()
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	chunk := make([]byte, size)
+	for {
+		n, err := io.ReadFull(body, chunk)
+		if n > 0 {
+			if e := fn(chunk[:n]); e != nil {
+				return e
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// Runes calls fn for each rune of response body converted to UTF-8.
+func (r *Response) Runes(fn func(c rune) error) error {
+	text, err := r.TextReader()
+	if err != nil {
+		return err
+	}
+	defer text.Close()
+	reader := bufio.NewReader(text)
+	for {
+		c, _, err := reader.ReadRune()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = fn(c); err != nil {
+			return err
+		}
+	}
+}
+
+// WriteTo writes response body decompressed by Content-Encoding to w.
+func (r *Response) WriteTo(w io.Writer) (int64, error) {
+	body, err := r.Reader()
+	if err != nil {
+		return 0, err
+	}
+	defer body.Close()
+	return io.Copy(w, body)
+}
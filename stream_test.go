@@ -0,0 +1,113 @@
+package hrq
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestStream(t *testing.T) {
+	sjis, _ := japanese.ShiftJIS.NewEncoder().String("日本\r\n語\nfoo")
+	compressed := compressForTest("gzip", []byte(sjis))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=Shift_JIS")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(compressed)
+	}))
+	defer server.Close()
+	get := func() *Response {
+		req, _ := Get(server.URL)
+		res, err := req.AcceptCompression().Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	lines := []string{}
+	res := get()
+	err := res.Lines(func(line string) error {
+		lines = append(lines, line)
+		return nil
+	})
+	if err != nil || strings.Join(lines, ",") != "日本,語,foo" {
+		t.Fatalf("Lines() is wrong. %#v %v", lines, err)
+	}
+	if _, err = res.Content(); err == nil {
+		t.Fatalf("Content() must fail after streaming.")
+	}
+	runes := []rune{}
+	get().Runes(func(c rune) error {
+		runes = append(runes, c)
+		return nil
+	})
+	if string(runes) != "日本\r\n語\nfoo" {
+		t.Fatalf("Runes() is wrong. %#v", string(runes))
+	}
+	chunks := []string{}
+	get().Chunks(5, func(chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	})
+	if strings.Join(chunks, "") != sjis || len(chunks) != 3 || len(chunks[2]) != 2 {
+		t.Fatalf("Chunks() is wrong. %#v", chunks)
+	}
+	res = get()
+	if err = res.Chunks(0, func(chunk []byte) error { return nil }); err == nil {
+		t.Fatalf("Chunks() must fail for size 0.")
+	}
+	if text, _ := res.Content(); string(text) != sjis {
+		t.Fatalf("body must not be consumed by invalid Chunks(). %#v", text)
+	}
+	var buffer bytes.Buffer
+	res = get()
+	n, err := res.WriteTo(&buffer)
+	if err != nil || n != int64(len(sjis)) || buffer.String() != sjis {
+		t.Fatalf("WriteTo() is wrong. %d %v", n, err)
+	}
+	// A cached body can be streamed many times.
+	res = get()
+	res.Content()
+	buffer.Reset()
+	res.WriteTo(&buffer)
+	if buffer.String() != sjis {
+		t.Fatalf("WriteTo() is wrong after Content(). %#v", buffer.String())
+	}
+}
+
+func TestStreamBodyLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/lines" {
+			io.WriteString(w, strings.Repeat("a\n", 51))
+			return
+		}
+		io.WriteString(w, strings.Repeat("a", 1000))
+	}))
+	defer server.Close()
+	req, _ := Get(server.URL)
+	res, _ := req.SetBodyLimit(&BodyLimit{MaxSize: 100}).Send()
+	var buffer bytes.Buffer
+	n, err := res.WriteTo(&buffer)
+	if e, ok := err.(*BodyTooLargeError); !ok || e.Kind != "decompressed" || n != 100 {
+		t.Fatalf("WriteTo() is wrong. %d %#v", n, err)
+	}
+	// The error is kept for readers which buffer the body.
+	req, _ = Get(server.URL + "/lines")
+	res, _ = req.SetBodyLimit(&BodyLimit{MaxSize: 100}).Send()
+	body, _ := res.Reader()
+	buffer.ReadFrom(body)
+	_, err = body.Read(make([]byte, 10))
+	if _, ok := err.(*BodyTooLargeError); !ok {
+		t.Fatalf("BodyTooLargeError must be returned again. %#v", err)
+	}
+	req, _ = Get(server.URL + "/lines")
+	res, _ = req.SetBodyLimit(&BodyLimit{MaxSize: 100}).Send()
+	err = res.Lines(func(line string) error { return nil })
+	if _, ok := err.(*BodyTooLargeError); !ok {
+		t.Fatalf("Lines() is wrong. %#v", err)
+	}
+}
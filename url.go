@@ -1,6 +1,13 @@
 package hrq
 
-import "net/url"
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
 
 func mapStringList(ms map[string]string) map[string][]string {
 	result := map[string][]string{}
@@ -10,10 +17,313 @@ func mapStringList(ms map[string]string) map[string][]string {
 	return result
 }
 
-// MakeURL makes url
+// MakeURL makes url.
+// The params are appended to the query of baseURL.
 func MakeURL(baseURL string, params map[string]string) string {
 	msl := mapStringList(params)
 	v := url.Values(msl)
-	u := baseURL + "?" + v.Encode()
-	return u
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return baseURL + "?" + v.Encode()
+	}
+	appendQuery(u, valuesFields(v))
+	return u.String()
+}
+
+// QueryValues converts v to url.Values.
+// v is map[string]string, url.Values, map[string][]string,
+// map[string]interface{}, Fields or a struct with `url:"name,omitempty"` tags.
+// The options of a tag are
+// "omitempty" (omit an empty value),
+// "comma" (join a slice with commas),
+// "brackets" (append "[]" to the name of a slice),
+// "int" (format bool as 1 or 0) and
+// "unix" (format time.Time as unix time).
+func QueryValues(v interface{}) (url.Values, error) {
+	fields, err := dataFields(v, "url")
+	if err != nil {
+		return nil, err
+	}
+	return fields.Values(), nil
+}
+
+// AppendQuery appends the query made from v to the query of rawURL.
+// v is converted like QueryValues and the fragment of rawURL is kept.
+func AppendQuery(rawURL string, v interface{}) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	fields, err := dataFields(v, "url")
+	if err != nil {
+		return "", err
+	}
+	appendQuery(u, fields)
+	return u.String(), nil
+}
+
+// AddQuery appends the query made from v to the request url.
+// v is converted like QueryValues.
+func (r *Request) AddQuery(v interface{}) error {
+	fields, err := dataFields(v, "url")
+	if err != nil {
+		return err
+	}
+	appendQuery(r.URL, fields)
+	return nil
+}
+
+func appendQuery(u *url.URL, fields Fields) {
+	if len(fields) == 0 {
+		return
+	}
+	query := []string{}
+	if u.RawQuery != "" {
+		query = append(query, u.RawQuery)
+	}
+	query = append(query, fields.Encode())
+	u.RawQuery = strings.Join(query, "&")
+	u.ForceQuery = false
+}
+
+// Vars are variables of a URI template.
+// A value is a string, a number, a bool, a slice (list),
+// a map with string keys or Fields (associative array).
+type Vars map[string]interface{}
+
+// uriOperator is an expression operator of RFC 6570.
+type uriOperator struct {
+	first    string
+	sep      string
+	named    bool
+	ifEmpty  string
+	reserved bool
+}
+
+var uriOperators = map[byte]uriOperator{
+	'+': {first: "", sep: ",", reserved: true},
+	'#': {first: "#", sep: ",", reserved: true},
+	'.': {first: ".", sep: "."},
+	'/': {first: "/", sep: "/"},
+	';': {first: ";", sep: ";", named: true},
+	'?': {first: "?", sep: "&", named: true, ifEmpty: "="},
+	'&': {first: "&", sep: "&", named: true, ifEmpty: "="},
+}
+
+// ExpandURL expands a URI template by RFC 6570 (Level 4).
+//
+//	ExpandURL("http://example.com/repos/{owner}/{repo}/issues{?state,labels*}", vars)
+func ExpandURL(template string, vars Vars) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			b.WriteString(encodeURIComponent(template, true))
+			return b.String(), nil
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("hrq: unclosed expression in URI template %q", template)
+		}
+		b.WriteString(encodeURIComponent(template[:start], true))
+		expanded, err := expandExpression(template[start+1:start+end], vars)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(expanded)
+		template = template[start+end+1:]
+	}
+}
+
+func expandURL(rawURL string, vars []Vars) (string, error) {
+	if len(vars) == 0 {
+		return rawURL, nil
+	}
+	merged := Vars{}
+	for _, v := range vars {
+		for k, value := range v {
+			merged[k] = value
+		}
+	}
+	return ExpandURL(rawURL, merged)
+}
+
+func expandExpression(expression string, vars Vars) (string, error) {
+	if expression == "" {
+		return "", errors.New("hrq: empty expression in URI template")
+	}
+	op := uriOperator{sep: ","}
+	if o, ok := uriOperators[expression[0]]; ok {
+		op = o
+		expression = expression[1:]
+	}
+	results := []string{}
+	for _, spec := range strings.Split(expression, ",") {
+		name, explode, prefix, err := parseVarspec(spec)
+		if err != nil {
+			return "", err
+		}
+		s, ok := expandVar(op, name, explode, prefix, vars[name])
+		if ok {
+			results = append(results, s)
+		}
+	}
+	if len(results) == 0 {
+		return "", nil
+	}
+	return op.first + strings.Join(results, op.sep), nil
+}
+
+func parseVarspec(spec string) (name string, explode bool, prefix int, err error) {
+	name = spec
+	if strings.HasSuffix(spec, "*") {
+		name = spec[:len(spec)-1]
+		explode = true
+	} else if i := strings.IndexByte(spec, ':'); i >= 0 {
+		name = spec[:i]
+		prefix, err = strconv.Atoi(spec[i+1:])
+		if err != nil || prefix <= 0 || prefix >= 10000 {
+			return "", false, 0, fmt.Errorf("hrq: invalid prefix in URI template %q", spec)
+		}
+	}
+	if name == "" {
+		return "", false, 0, fmt.Errorf("hrq: invalid variable in URI template %q", spec)
+	}
+	for _, c := range name {
+		if !(isUnreserved(c) && c != '-' && c != '~' || c == '%') {
+			return "", false, 0, fmt.Errorf("hrq: invalid variable in URI template %q", spec)
+		}
+	}
+	return name, explode, prefix, nil
+}
+
+// expandVar expands a variable. ok is false when the variable is undefined.
+func expandVar(op uriOperator, name string, explode bool, prefix int, value interface{}) (s string, ok bool) {
+	list, pairs, isString := uriValue(value)
+	encode := func(s string) string {
+		return encodeURIComponent(s, op.reserved)
+	}
+	named := func(key, value string) string {
+		if value == "" {
+			return key + op.ifEmpty
+		}
+		return key + "=" + value
+	}
+	switch {
+	case value == nil:
+		return "", false
+	case isString:
+		v := list[0]
+		if prefix > 0 {
+			if runes := []rune(v); len(runes) > prefix {
+				v = string(runes[:prefix])
+			}
+		}
+		if op.named {
+			return named(name, encode(v)), true
+		}
+		return encode(v), true
+	case pairs != nil:
+		if len(pairs) == 0 {
+			return "", false
+		}
+		items := []string{}
+		for _, p := range pairs {
+			if explode {
+				items = append(items, named(encode(p.Name), encode(p.Value)))
+			} else {
+				items = append(items, encode(p.Name), encode(p.Value))
+			}
+		}
+		if explode {
+			return strings.Join(items, op.sep), true
+		}
+		if op.named {
+			return named(name, strings.Join(items, ",")), true
+		}
+		return strings.Join(items, ","), true
+	default:
+		if len(list) == 0 {
+			return "", false
+		}
+		items := []string{}
+		for _, v := range list {
+			if explode && op.named {
+				items = append(items, named(name, encode(v)))
+			} else {
+				items = append(items, encode(v))
+			}
+		}
+		if explode {
+			return strings.Join(items, op.sep), true
+		}
+		if op.named {
+			return named(name, strings.Join(items, ",")), true
+		}
+		return strings.Join(items, ","), true
+	}
+}
+
+// uriValue classifies a variable into a string, a list or an associative array.
+func uriValue(value interface{}) (list []string, pairs Fields, isString bool) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil, false
+	case string:
+		return []string{v}, nil, true
+	case Fields:
+		return nil, v, false
+	case []string:
+		return v, nil, false
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			list = append(list, fmt.Sprint(rv.Index(i).Interface()))
+		}
+		return list, nil, false
+	case reflect.Map:
+		pairs = Fields{}
+		for _, k := range sortedKeys(rv) {
+			v := rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key()))
+			pairs = pairs.Add(k, fmt.Sprint(v.Interface()))
+		}
+		return nil, pairs, false
+	}
+	return []string{fmt.Sprint(value)}, nil, true
+}
+
+func isUnreserved(c rune) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isReserved(c byte) bool {
+	return strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// encodeURIComponent percent-encodes s.
+// When reserved is true, reserved characters and percent-encoded triplets are kept.
+func encodeURIComponent(s string, reserved bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c < 0x80 && isUnreserved(rune(c)):
+			b.WriteByte(c)
+		case reserved && isReserved(c):
+			b.WriteByte(c)
+		case reserved && c == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteString(s[i : i+3])
+			i += 2
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
 }
@@ -0,0 +1,186 @@
+package hrq
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMakeURL(t *testing.T) {
+	params := map[string]string{
+		"b": "2",
+		"a": "&1",
+	}
+	u := MakeURL("http://example.com/foo?c=3#bar", params)
+	if u != "http://example.com/foo?c=3&a=%261&b=2#bar" {
+		t.Fatalf("MakeURL() is wrong. u is %#v", u)
+	}
+	u = MakeURL("http://example.com", params)
+	if u != "http://example.com?a=%261&b=2" {
+		t.Fatalf("MakeURL() is wrong. u is %#v", u)
+	}
+}
+
+type queryOptions struct {
+	Query   string    `url:"q"`
+	Page    int       `url:"page,omitempty"`
+	Labels  []string  `url:"labels,comma"`
+	IDs     []int     `url:"id,brackets"`
+	States  []string  `url:"state"`
+	Archive bool      `url:"archived,int"`
+	Since   time.Time `url:"since,omitempty"`
+	Filter  struct {
+		Owner string `url:"owner"`
+	} `url:"filter"`
+}
+
+func TestQueryValues(t *testing.T) {
+	opt := queryOptions{
+		Query:   "foo bar",
+		Labels:  []string{"bug", "ui"},
+		IDs:     []int{1, 2},
+		States:  []string{"open", "closed"},
+		Archive: true,
+	}
+	opt.Filter.Owner = "me"
+	u, err := AppendQuery("http://example.com/?x=1#top", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "http://example.com/?x=1&q=foo+bar&labels=bug%2Cui&id%5B%5D=1&id%5B%5D=2&state=open&state=closed&archived=1&filter%5Bowner%5D=me#top"
+	if u != want {
+		t.Fatalf("AppendQuery() is wrong. u is %#v", u)
+	}
+	values, _ := QueryValues(opt)
+	if values.Get("q") != "foo bar" || len(values["state"]) != 2 || values.Get("since") != "" {
+		t.Fatalf("QueryValues() is wrong. values is %#v", values)
+	}
+	req, _ := Get("http://example.com/?x=1")
+	if err = req.AddQuery(map[string][]string{"y": {"2", "3"}}); err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.String() != "http://example.com/?x=1&y=2&y=3" {
+		t.Fatalf("AddQuery() is wrong. url is %#v", req.URL.String())
+	}
+	if _, err = QueryValues("foo"); err == nil {
+		t.Fatalf("QueryValues() must fail for string.")
+	}
+}
+
+func TestExpandURL(t *testing.T) {
+	vars := Vars{
+		"count":      []string{"one", "two", "three"},
+		"dom":        []string{"example", "com"},
+		"dub":        "me/too",
+		"hello":      "Hello World!",
+		"half":       "50%",
+		"var":        "value",
+		"who":        "fred",
+		"base":       "http://example.com/home/",
+		"path":       "/foo/bar",
+		"list":       []string{"red", "green", "blue"},
+		"keys":       map[string]string{"semi": ";", "dot": ".", "comma": ","},
+		"v":          6,
+		"x":          1024,
+		"y":          768,
+		"empty":      "",
+		"empty_keys": map[string]string{},
+		"undef":      nil,
+	}
+	cases := [][]string{
+		{"{var}", "value"},
+		{"{hello}", "Hello%20World%21"},
+		{"{half}", "50%25"},
+		{"O{empty}X", "OX"},
+		{"O{undef}X", "OX"},
+		{"{x,y}", "1024,768"},
+		{"{x,hello,y}", "1024,Hello%20World%21,768"},
+		{"?{x,empty}", "?1024,"},
+		{"?{x,undef}", "?1024"},
+		{"{var:3}", "val"},
+		{"{var:30}", "value"},
+		{"{list}", "red,green,blue"},
+		{"{list*}", "red,green,blue"},
+		{"{keys}", "comma,%2C,dot,.,semi,%3B"},
+		{"{keys*}", "comma=%2C,dot=.,semi=%3B"},
+		{"{+var}", "value"},
+		{"{+hello}", "Hello%20World!"},
+		{"{+half}", "50%25"},
+		{"{base}index", "http%3A%2F%2Fexample.com%2Fhome%2Findex"},
+		{"{+base}index", "http://example.com/home/index"},
+		{"{+path}/here", "/foo/bar/here"},
+		{"{+path:6}/here", "/foo/b/here"},
+		{"{+list*}", "red,green,blue"},
+		{"{+keys*}", "comma=,,dot=.,semi=;"},
+		{"{#var}", "#value"},
+		{"{#hello}", "#Hello%20World!"},
+		{"{#path:6}/here", "#/foo/b/here"},
+		{"{#keys}", "#comma,,,dot,.,semi,;"},
+		{"X{.var}", "X.value"},
+		{"X{.x,y}", "X.1024.768"},
+		{"{.who,who}", ".fred.fred"},
+		{"X{.list*}", "X.red.green.blue"},
+		{"X{.empty_keys}", "X"},
+		{"{/who,who}", "/fred/fred"},
+		{"{/var,empty}", "/value/"},
+		{"{/var:1,var}", "/v/value"},
+		{"{/list*,path:4}", "/red/green/blue/%2Ffoo"},
+		{"{/keys*}", "/comma=%2C/dot=./semi=%3B"},
+		{"{;x,y,empty}", ";x=1024;y=768;empty"},
+		{"{;v,empty,who}", ";v=6;empty;who=fred"},
+		{"{;hello:5}", ";hello=Hello"},
+		{"{;list*}", ";list=red;list=green;list=blue"},
+		{"{;keys*}", ";comma=%2C;dot=.;semi=%3B"},
+		{"{?x,y,empty}", "?x=1024&y=768&empty="},
+		{"{?var:3}", "?var=val"},
+		{"{?list}", "?list=red,green,blue"},
+		{"{?list*}", "?list=red&list=green&list=blue"},
+		{"{?keys}", "?keys=comma,%2C,dot,.,semi,%3B"},
+		{"{?keys*}", "?comma=%2C&dot=.&semi=%3B"},
+		{"?fixed=yes{&x}", "?fixed=yes&x=1024"},
+		{"{&var:3}", "&var=val"},
+		{"{&list*}", "&list=red&list=green&list=blue"},
+		{"find{?year*}", "find"},
+		{"{count}", "one,two,three"},
+		{"{/count*}", "/one/two/three"},
+		{"{.dom*}", ".example.com"},
+		{"{;count*}", ";count=one;count=two;count=three"},
+		{"{#dub}", "#me/too"},
+	}
+	for _, c := range cases {
+		u, err := ExpandURL(c[0], vars)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if u != c[1] {
+			t.Fatalf("ExpandURL(%#v) is wrong. want %#v, got %#v", c[0], c[1], u)
+		}
+	}
+	for _, template := range []string{"{var", "{}", "{var:x}", "{v-r}"} {
+		if _, err := ExpandURL(template, vars); err == nil {
+			t.Fatalf("ExpandURL(%#v) must fail.", template)
+		}
+	}
+}
+
+func TestURLTemplateRequest(t *testing.T) {
+	vars := map[string]interface{}{
+		"owner":  "windy server",
+		"repo":   "hrq",
+		"state":  "open",
+		"labels": map[string]string{"bug": "1"},
+	}
+	req, err := Get("https://api.example.com/repos/{owner}/{repo}/issues{?state,labels*}", vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL.String() != "https://api.example.com/repos/windy%20server/hrq/issues?state=open&bug=1" {
+		t.Fatalf("url is wrong. url is %#v", req.URL.String())
+	}
+	req, _ = Post("http://example.com/{id}", nil, Vars{"id": 1})
+	if req.URL.Path != "/1" {
+		t.Fatalf("url is wrong. url is %#v", req.URL.String())
+	}
+	if _, err = Get("http://example.com/{id", vars); err == nil {
+		t.Fatalf("Get() must fail for invalid template.")
+	}
+}
@@ -0,0 +1,104 @@
+package hrq
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"regexp"
+
+	"golang.org/x/net/html/charset"
+)
+
+type xmlCodec struct{}
+
+// Marshal encodes v with the XML declaration.
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	b, err := xml.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// Unmarshal decodes data in the encoding of its XML declaration.
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charset.NewReaderLabel
+	return decoder.Decode(v)
+}
+
+func init() {
+	RegisterCodec(applicationXML, xmlCodec{})
+	RegisterCodec("text/xml", xmlCodec{})
+	RegisterCodec("+xml", xmlCodec{})
+}
+
+var xmlDeclarationEncoding = regexp.MustCompile(`^\s*<\?xml[^>]*?\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// xmlEncoding determines the encoding of a XML document.
+// The charset of content-type and BOM take precedence over the XML declaration.
+// (default UTF-8)
+func xmlEncoding(body []byte, contentType string) string {
+	_, name, certain := charset.DetermineEncoding(body, contentType)
+	if certain {
+		return name
+	}
+	if m := xmlDeclarationEncoding.FindSubmatch(body); m != nil {
+		return string(m[1])
+	}
+	return "utf-8"
+}
+
+// XMLDecoder returns a xml.Decoder which reads response body converted to UTF-8.
+func (r *Response) XMLDecoder() (*xml.Decoder, error) {
+	body, err := r.Content()
+	if err != nil {
+		return nil, err
+	}
+	reader, err := charset.NewReaderLabel(xmlEncoding(body, r.ContentType()), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	decoder := xml.NewDecoder(reader)
+	// The body is already converted, so the encoding of the declaration is ignored.
+	decoder.CharsetReader = func(label string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder, nil
+}
+
+// XML returns unmarshal response body.
+// The encoding is determined by content-type, BOM or the XML declaration.
+func (r *Response) XML(v interface{}) error {
+	decoder, err := r.XMLDecoder()
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(v)
+}
+
+// XMLElements calls fn for each element whose namespace is space and name is local.
+// If space is empty, an element in any namespace matches.
+// fn can decode the element by decoder.DecodeElement(v, &start).
+func (r *Response) XMLElements(space, local string, fn func(decoder *xml.Decoder, start xml.StartElement) error) error {
+	decoder, err := r.XMLDecoder()
+	if err != nil {
+		return err
+	}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != local || (space != "" && start.Name.Space != space) {
+			continue
+		}
+		if err = fn(decoder, start); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package hrq
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+type xmlItem struct {
+	XMLName xml.Name `xml:"item"`
+	Title   string   `xml:"title"`
+}
+
+func TestXMLRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+		fmt.Fprintf(w, "%s", b)
+	}))
+	defer server.Close()
+	req, _ := Post(server.URL, &xmlItem{Title: "foo"})
+	res, err := req.SetApplicationXML().Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := res.Text()
+	if text != xml.Header+"<item><title>foo</title></item>" {
+		t.Fatalf("XML request is wrong. %#v", text)
+	}
+	var item xmlItem
+	if err = res.XML(&item); err != nil {
+		t.Fatal(err)
+	}
+	if item.Title != "foo" {
+		t.Fatalf("XML() is wrong. %#v", item)
+	}
+	item = xmlItem{}
+	if err = res.Decode(&item); err != nil || item.Title != "foo" {
+		t.Fatalf("Decode() is wrong. %#v", item)
+	}
+}
+
+func TestXMLEncoding(t *testing.T) {
+	sjis, _ := japanese.ShiftJIS.NewEncoder().String("<item><title>日本語</title></item>")
+	eucjp, _ := japanese.EUCJP.NewEncoder().String("<item><title>日本語</title></item>")
+	bodies := map[string][]string{
+		"header":      {"text/xml; charset=Shift_JIS", `<?xml version="1.0"?>` + sjis},
+		"declaration": {"application/xml", `<?xml version="1.0" encoding="EUC-JP"?>` + eucjp},
+		"both":        {"text/xml; charset=EUC-JP", `<?xml version="1.0" encoding="Shift_JIS"?>` + eucjp},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := bodies[r.URL.Query().Get("case")]
+		w.Header().Set("Content-Type", body[0])
+		fmt.Fprint(w, body[1])
+	}))
+	defer server.Close()
+	for name := range bodies {
+		req, _ := Get(MakeURL(server.URL, map[string]string{"case": name}))
+		res, _ := req.Send()
+		var item xmlItem
+		if err := res.XML(&item); err != nil {
+			t.Fatalf("XML() is wrong. %s %v", name, err)
+		}
+		if item.Title != "日本語" {
+			t.Fatalf("XML() is wrong. %s %#v", name, item.Title)
+		}
+	}
+}
+
+func TestXMLElements(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/atom+xml")
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xmlns:m="urn:media">
+<entry><title>a</title><m:title>x</m:title></entry>
+<entry><title>b</title></entry>
+</feed>`)
+	}))
+	defer server.Close()
+	req, _ := Get(server.URL)
+	res, _ := req.Send()
+	titles := []string{}
+	err := res.XMLElements("http://www.w3.org/2005/Atom", "title", func(decoder *xml.Decoder, start xml.StartElement) error {
+		var title string
+		err := decoder.DecodeElement(&title, &start)
+		titles = append(titles, title)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(titles) != 2 || titles[0] != "a" || titles[1] != "b" {
+		t.Fatalf("XMLElements() is wrong. %#v", titles)
+	}
+	count := 0
+	res.XMLElements("", "title", func(decoder *xml.Decoder, start xml.StartElement) error {
+		count++
+		return nil
+	})
+	if count != 3 {
+		t.Fatalf("XMLElements() is wrong. count is %d", count)
+	}
+}